@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ninepub/kafka-mock/pkg/types"
+	"gopkg.in/yaml.v2"
+)
+
+// config is the file passed with --config, in YAML or JSON
+type config struct {
+	Topics []topicConfig `json:"topics" yaml:"topics"`
+	SASL   *types.SASL   `json:"sasl" yaml:"sasl"`
+	TLS    *types.TLS    `json:"tls" yaml:"tls"`
+
+	Listeners []types.Listener `json:"listeners" yaml:"listeners"`
+	Faults    []types.Fault    `json:"faults" yaml:"faults"`
+}
+
+// topicConfig accepts topic configs of any scalar type, like retention.ms: 60000
+type topicConfig struct {
+	Name              string                 `json:"name" yaml:"name"`
+	Partitions        int32                  `json:"partitions" yaml:"partitions"`
+	ReplicationFactor int16                  `json:"replicationFactor" yaml:"replicationFactor"`
+	Configs           map[string]interface{} `json:"configs" yaml:"configs"`
+}
+
+// loadConfig reads a config file, files ending in .json are parsed as JSON and
+// all others as YAML
+func loadConfig(path string) (*config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &config{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.UseNumber()
+		d.DisallowUnknownFields()
+		err = d.Decode(c)
+	} else {
+		err = yaml.UnmarshalStrict(data, c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, err)
+	}
+	return c, nil
+}
+
+// topics converts the topics of the config file to the server params
+func (c *config) topics() []types.Topic {
+	topics := make([]types.Topic, 0, len(c.Topics))
+	for _, t := range c.Topics {
+		topic := types.Topic{Name: t.Name, Partitions: t.Partitions, ReplicationFactor: t.ReplicationFactor, Configs: map[string]string{}}
+		for name, value := range t.Configs {
+			topic.Configs[name] = fmt.Sprint(value)
+		}
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// parseListeners parses listeners in the format of the listeners config of Kafka,
+// like PLAINTEXT://0.0.0.0:9092,EXTERNAL://0.0.0.0:9093
+func parseListeners(s string) ([]types.Listener, error) {
+	var listeners []types.Listener
+	for _, entry := range strings.Split(s, ",") {
+		name, host, port, err := parseListener(entry)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, types.Listener{Name: name, Addr: host, Port: port})
+	}
+	return listeners, nil
+}
+
+// advertiseListeners sets the advertised addresses of listeners from the format of the
+// advertised.listeners config of Kafka, like PLAINTEXT://kafka:9092,EXTERNAL://localhost:9093
+func advertiseListeners(listeners []types.Listener, s string) error {
+	for _, entry := range strings.Split(s, ",") {
+		name, host, port, err := parseListener(entry)
+		if err != nil {
+			return err
+		}
+		found := false
+		for i := range listeners {
+			if listeners[i].Name == name {
+				listeners[i].AdvertisedHost = host
+				listeners[i].AdvertisedPort = port
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("advertised listener %s is not a listener", name)
+		}
+	}
+	return nil
+}
+
+// parseListener parses a single NAME://host:port listener
+func parseListener(s string) (string, string, int, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), "://", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", 0, fmt.Errorf("invalid listener %q", s)
+	}
+	host, port, err := net.SplitHostPort(parts[1])
+	if err != nil {
+		return "", "", 0, fmt.Errorf("invalid listener %q: %s", s, err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("invalid listener %q: %s", s, err)
+	}
+	return parts[0], host, p, nil
+}
@@ -5,26 +5,84 @@ import (
 	"github.com/ninepub/kafka-mock/pkg/server"
 	"github.com/ninepub/kafka-mock/pkg/types"
 
+	"context"
 	"flag"
 	"fmt"
+	"os"
 )
 
 var addr = flag.String("addr", "", "The address to listen to; default is \"\" (all interfaces).")
 var port = flag.Int("port", 9092, "The port to listen on; default is 9092.")
 var topic = flag.String("topic", "mock", "The default mock topic created.")
+var brokers = flag.Int("brokers", 1, "The number of brokers, listening on the given ports plus their index times the number of listeners; default is 1.")
+var replicationFactor = flag.Int("default-replication-factor", 1, "The replication factor of topics created without one; default is 1.")
+var listeners = flag.String("listeners", "", "Named listeners replacing --addr and --port, like PLAINTEXT://0.0.0.0:9092,EXTERNAL://0.0.0.0:9093.")
+var advertisedListeners = flag.String("advertised-listeners", "", "The addresses advertised by the listeners, like PLAINTEXT://kafka:9092,EXTERNAL://localhost:9093.")
+var configFile = flag.String("config", "", "A YAML or JSON file declaring the topics to create, the SASL users, the TLS settings, the listeners and the faults to inject.")
 
 func main() {
 	flag.Parse()
-	data := make(chan map[string][]byte)
-	server.StartKafka(&types.Params{Addr: *addr, Port: *port, Data: data, Topic: *topic})
+	records := make(chan []*types.Record)
+	params := &types.Params{
+		Addr:                     *addr,
+		Port:                     *port,
+		Records:                  records,
+		Topic:                    *topic,
+		Brokers:                  *brokers,
+		DefaultReplicationFactor: int16(*replicationFactor),
+	}
+	if *configFile != "" {
+		c, err := loadConfig(*configFile)
+		if err != nil {
+			fmt.Printf("Failed to load the config: %s\n", err)
+			os.Exit(1)
+		}
+		params.Topics = c.topics()
+		params.SASL = c.SASL
+		params.TLS = c.TLS
+		params.Listeners = c.Listeners
+		params.Faults = c.Faults
+	}
+	if err := setListeners(params); err != nil {
+		fmt.Printf("Invalid listeners: %s\n", err)
+		os.Exit(1)
+	}
+	s := server.New(params)
+	if err := s.Start(context.Background()); err != nil {
+		fmt.Printf("Failed to start the server: %s\n", err)
+		os.Exit(1)
+	}
+	// Clients have to trust the generated CA, unless the broker has its own certificate
+	if params.TLS != nil && params.TLS.CertFile == "" {
+		fmt.Printf("TLS CA certificate:\n%s", s.CACertificate())
+	}
 	for {
-		records := <-data
+		batch := <-records
 		// Handle the received records here
-		fmt.Println("Total Records: ", len(records))
-		for key, value := range records {
-			fmt.Println("Key : ", key)
-			fmt.Println("Value (length) : ", len(value))
-			// fmt.Println("Value (In bytes) : ", value)
+		fmt.Println("Total Records: ", len(batch))
+		for _, record := range batch {
+			fmt.Println("Topic : ", record.Topic, "Partition : ", record.Partition, "Offset : ", record.Offset)
+			fmt.Println("Key : ", string(record.Key))
+			fmt.Println("Value (length) : ", len(record.Value))
+			// fmt.Println("Value (In bytes) : ", record.Value)
+		}
+	}
+}
+
+// setListeners applies the listener flags to the params
+func setListeners(params *types.Params) error {
+	if *listeners != "" {
+		l, err := parseListeners(*listeners)
+		if err != nil {
+			return err
+		}
+		params.Listeners = l
+	}
+	if *advertisedListeners != "" {
+		if len(params.Listeners) == 0 {
+			return fmt.Errorf("--advertised-listeners requires listeners")
 		}
+		return advertiseListeners(params.Listeners, *advertisedListeners)
 	}
+	return nil
 }
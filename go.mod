@@ -7,4 +7,5 @@ require (
 	github.com/golang/snappy v0.0.1 // indirect
 	github.com/klauspost/compress v1.9.7
 	github.com/pierrec/lz4 v2.4.0+incompatible
+	gopkg.in/yaml.v2 v2.4.0
 )
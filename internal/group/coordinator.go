@@ -0,0 +1,611 @@
+// Consumer group coordination
+package group
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+)
+
+const (
+	// Defaults of group.min.session.timeout.ms and group.max.session.timeout.ms
+	DefaultMinSessionTimeout = 6 * time.Second
+	DefaultMaxSessionTimeout = 30 * time.Minute
+)
+
+// Config holds the group coordinator settings of a broker
+type Config struct {
+	// InitialRebalanceDelay delays the first rebalance of an empty group so that
+	// more members can join it, like group.initial.rebalance.delay.ms
+	InitialRebalanceDelay time.Duration
+	// MinSessionTimeout and MaxSessionTimeout bound the session timeouts members may ask for
+	MinSessionTimeout time.Duration
+	MaxSessionTimeout time.Duration
+}
+
+type JoinRequest struct {
+	GroupID          string
+	MemberID         string
+	GroupInstanceID  string
+	ClientID         string
+	ClientHost       string
+	SessionTimeout   time.Duration
+	RebalanceTimeout time.Duration
+	ProtocolType     string
+	Protocols        []Protocol
+	// RequireKnownMemberID makes new dynamic members rejoin with the member id
+	// returned along with a member id required error, as JoinGroup v4+ does
+	RequireKnownMemberID bool
+}
+
+type MemberMetadata struct {
+	MemberID        string
+	GroupInstanceID string
+	Metadata        []byte
+}
+
+type JoinResult struct {
+	Err          error
+	GenerationID int32
+	Protocol     string
+	LeaderID     string
+	MemberID     string
+	// Members is only sent to the leader, which computes the assignments
+	Members []*MemberMetadata
+}
+
+type SyncRequest struct {
+	GroupID         string
+	GenerationID    int32
+	MemberID        string
+	GroupInstanceID string
+	// Assignments is only sent by the leader
+	Assignments map[string][]byte
+}
+
+type SyncResult struct {
+	Err        error
+	Assignment []byte
+}
+
+// LeavingMember identifies a member leaving a group by member id or group instance id
+type LeavingMember struct {
+	MemberID        string
+	GroupInstanceID string
+}
+
+// Coordinator runs the rebalance protocol of every consumer group of a broker
+type Coordinator struct {
+	config  Config
+	offsets *OffsetStore
+
+	mu     sync.Mutex
+	groups map[string]*group
+	closed bool
+}
+
+func NewCoordinator(config Config) *Coordinator {
+	if config.MinSessionTimeout == 0 {
+		config.MinSessionTimeout = DefaultMinSessionTimeout
+	}
+	if config.MaxSessionTimeout == 0 {
+		config.MaxSessionTimeout = DefaultMaxSessionTimeout
+	}
+	return &Coordinator{
+		config:  config,
+		offsets: NewOffsetStore(),
+		groups:  make(map[string]*group),
+	}
+}
+
+// Join adds a member to a group or updates it. The result is sent once the
+// rebalance the member takes part in completes.
+func (c *Coordinator) Join(req *JoinRequest) <-chan *JoinResult {
+	ch := make(chan *JoinResult, 1)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.validateJoin(req); err != nil {
+		ch <- &JoinResult{Err: err, GenerationID: -1, MemberID: req.MemberID}
+		return ch
+	}
+	g := c.groups[req.GroupID]
+	if g == nil {
+		g = newGroup(req.GroupID)
+		c.groups[req.GroupID] = g
+	}
+	if req.MemberID == "" {
+		c.joinNewMember(g, req, ch)
+	} else {
+		c.joinKnownMember(g, req, ch)
+	}
+	return ch
+}
+
+func (c *Coordinator) validateJoin(req *JoinRequest) error {
+	switch {
+	case c.closed:
+		return protocol.ErrCoordinatorNotAvailable
+	case req.GroupID == "":
+		return protocol.ErrInvalidGroupId
+	case req.SessionTimeout < c.config.MinSessionTimeout || req.SessionTimeout > c.config.MaxSessionTimeout:
+		return protocol.ErrInvalidSessionTimeout
+	case req.ProtocolType == "" || len(req.Protocols) == 0:
+		return protocol.ErrInconsistentGroupProtocol
+	}
+	return nil
+}
+
+func (c *Coordinator) joinNewMember(g *group, req *JoinRequest, ch chan *JoinResult) {
+	if !g.supports(req, nil) {
+		ch <- &JoinResult{Err: protocol.ErrInconsistentGroupProtocol, GenerationID: -1}
+		return
+	}
+	id := newMemberID(req.ClientID)
+
+	if req.GroupInstanceID != "" {
+		if old, ok := g.instances[req.GroupInstanceID]; ok {
+			// A restarted static member takes over the membership of its previous
+			// instance, which is fenced
+			c.replaceMember(g, g.members[old], id)
+			known := *req
+			known.MemberID = id
+			c.joinKnownMember(g, &known, ch)
+			return
+		}
+	} else if req.RequireKnownMemberID {
+		g.pending[id] = struct{}{}
+		time.AfterFunc(req.SessionTimeout, func() {
+			c.mu.Lock()
+			delete(g.pending, id)
+			c.mu.Unlock()
+		})
+		ch <- &JoinResult{Err: protocol.ErrMemberIdRequired, GenerationID: -1, MemberID: id}
+		return
+	}
+	c.addMember(g, req, id, ch)
+}
+
+func (c *Coordinator) joinKnownMember(g *group, req *JoinRequest, ch chan *JoinResult) {
+	if _, ok := g.pending[req.MemberID]; ok {
+		delete(g.pending, req.MemberID)
+		if !g.supports(req, nil) {
+			ch <- &JoinResult{Err: protocol.ErrInconsistentGroupProtocol, GenerationID: -1, MemberID: req.MemberID}
+			return
+		}
+		c.addMember(g, req, req.MemberID, ch)
+		return
+	}
+
+	m := g.members[req.MemberID]
+	if err := g.checkMember(m, req.MemberID, req.GroupInstanceID); err != nil {
+		ch <- &JoinResult{Err: err, GenerationID: -1, MemberID: req.MemberID}
+		return
+	}
+	if !g.supports(req, m) {
+		ch <- &JoinResult{Err: protocol.ErrInconsistentGroupProtocol, GenerationID: -1, MemberID: req.MemberID}
+		return
+	}
+
+	switch g.state {
+	case PreparingRebalance:
+		if m.join != nil {
+			m.join <- &JoinResult{Err: protocol.ErrRebalanceInProgress, GenerationID: -1, MemberID: m.id}
+		}
+		m.update(req)
+		m.join = ch
+		c.tryCompleteJoin(g)
+	case CompletingRebalance, Stable:
+		// A follower that rejoins without changes gets the current generation,
+		// the leader always triggers a new rebalance
+		if m.sameProtocols(req.Protocols) && (g.state == CompletingRebalance || m.id != g.leader) {
+			ch <- g.joinResult(m)
+			return
+		}
+		m.update(req)
+		m.join = ch
+		c.rebalance(g)
+	}
+}
+
+func (c *Coordinator) addMember(g *group, req *JoinRequest, id string, ch chan *JoinResult) {
+	m := &member{id: id, instanceID: req.GroupInstanceID, join: ch}
+	m.update(req)
+	if len(g.members) == 0 {
+		g.protocolType = req.ProtocolType
+	}
+	g.members[id] = m
+	g.order = append(g.order, id)
+	if m.instanceID != "" {
+		g.instances[m.instanceID] = id
+	}
+	c.rebalance(g)
+}
+
+func (c *Coordinator) replaceMember(g *group, m *member, id string) {
+	if m.join != nil {
+		m.join <- &JoinResult{Err: protocol.ErrFencedInstanceId, GenerationID: -1, MemberID: m.id}
+		m.join = nil
+	}
+	if m.sync != nil {
+		m.sync <- &SyncResult{Err: protocol.ErrFencedInstanceId}
+		m.sync = nil
+	}
+	old := m.id
+	delete(g.members, old)
+	g.members[id] = m
+	for i := range g.order {
+		if g.order[i] == old {
+			g.order[i] = id
+		}
+	}
+	g.instances[m.instanceID] = id
+	if g.leader == old {
+		g.leader = id
+	}
+	m.id = id
+}
+
+// rebalance moves the group to PreparingRebalance, members have until the
+// longest rebalance timeout to rejoin
+func (c *Coordinator) rebalance(g *group) {
+	if g.state == PreparingRebalance {
+		c.tryCompleteJoin(g)
+		return
+	}
+	if g.state == CompletingRebalance {
+		for _, m := range g.members {
+			if m.sync != nil {
+				m.sync <- &SyncResult{Err: protocol.ErrRebalanceInProgress}
+				m.sync = nil
+			}
+		}
+	}
+	for _, m := range g.members {
+		m.assignment = nil
+	}
+
+	delay := g.rebalanceTimeout()
+	if g.state == Empty && c.config.InitialRebalanceDelay > 0 {
+		g.initialDelay = true
+		if c.config.InitialRebalanceDelay < delay {
+			delay = c.config.InitialRebalanceDelay
+		}
+	}
+	g.state = PreparingRebalance
+
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if g.rebalanceTimer == timer {
+			c.completeJoin(g)
+		}
+	})
+	g.rebalanceTimer = timer
+	c.tryCompleteJoin(g)
+}
+
+// tryCompleteJoin completes the join phase once every member has rejoined
+func (c *Coordinator) tryCompleteJoin(g *group) {
+	if g.state != PreparingRebalance || g.initialDelay {
+		return
+	}
+	for _, m := range g.members {
+		if m.join == nil {
+			return
+		}
+	}
+	c.completeJoin(g)
+}
+
+// completeJoin starts a new generation with the members that rejoined and
+// answers their join requests
+func (c *Coordinator) completeJoin(g *group) {
+	if g.rebalanceTimer != nil {
+		g.rebalanceTimer.Stop()
+		g.rebalanceTimer = nil
+	}
+	g.initialDelay = false
+
+	for _, m := range g.members {
+		if m.join == nil {
+			c.removeMember(g, m)
+		}
+	}
+
+	g.generation++
+	if len(g.members) == 0 {
+		g.state = Empty
+		g.protocolType = ""
+		g.protocol = ""
+		return
+	}
+	g.protocol = g.selectProtocol()
+	if g.leader == "" {
+		g.leader = g.order[0]
+	}
+	g.state = CompletingRebalance
+
+	for _, id := range g.order {
+		m := g.members[id]
+		m.join <- g.joinResult(m)
+		m.join = nil
+		c.heartbeat(g, m)
+	}
+}
+
+// Sync hands the assignments of the leader to the members. Followers wait
+// until the leader has sent them.
+func (c *Coordinator) Sync(req *SyncRequest) <-chan *SyncResult {
+	ch := make(chan *SyncResult, 1)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g := c.groups[req.GroupID]
+	if c.closed || g == nil {
+		ch <- &SyncResult{Err: c.missingGroupError()}
+		return ch
+	}
+	m := g.members[req.MemberID]
+	if err := g.checkMember(m, req.MemberID, req.GroupInstanceID); err != nil {
+		ch <- &SyncResult{Err: err}
+		return ch
+	}
+	if req.GenerationID != g.generation {
+		ch <- &SyncResult{Err: protocol.ErrIllegalGeneration}
+		return ch
+	}
+
+	switch g.state {
+	case PreparingRebalance:
+		ch <- &SyncResult{Err: protocol.ErrRebalanceInProgress}
+		return ch
+	case CompletingRebalance:
+		m.sync = ch
+		if m.id == g.leader {
+			for id, other := range g.members {
+				other.assignment = req.Assignments[id]
+				if other.assignment == nil {
+					other.assignment = []byte{}
+				}
+			}
+			g.state = Stable
+			for _, other := range g.members {
+				if other.sync != nil {
+					other.sync <- &SyncResult{Assignment: other.assignment}
+					other.sync = nil
+				}
+			}
+		}
+	case Stable:
+		ch <- &SyncResult{Assignment: m.assignment}
+	}
+	c.heartbeat(g, m)
+	return ch
+}
+
+// Heartbeat keeps the session of a member alive and tells it about rebalances
+func (c *Coordinator) Heartbeat(groupID string, generationID int32, memberID, groupInstanceID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g := c.groups[groupID]
+	if c.closed || g == nil {
+		return c.missingGroupError()
+	}
+	m := g.members[memberID]
+	if err := g.checkMember(m, memberID, groupInstanceID); err != nil {
+		return err
+	}
+	if generationID != g.generation {
+		return protocol.ErrIllegalGeneration
+	}
+	c.heartbeat(g, m)
+	if g.state == PreparingRebalance {
+		return protocol.ErrRebalanceInProgress
+	}
+	return nil
+}
+
+// Leave removes members from a group, returning an error for each of them
+func (c *Coordinator) Leave(groupID string, members []*LeavingMember) []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	errs := make([]error, len(members))
+	g := c.groups[groupID]
+	if c.closed || g == nil {
+		for i := range errs {
+			errs[i] = c.missingGroupError()
+		}
+		return errs
+	}
+
+	left := false
+	for i, lm := range members {
+		id := lm.MemberID
+		if lm.GroupInstanceID != "" {
+			instanceMember, ok := g.instances[lm.GroupInstanceID]
+			if !ok {
+				errs[i] = protocol.ErrUnknownMemberId
+				continue
+			}
+			if id != "" && id != instanceMember {
+				errs[i] = protocol.ErrFencedInstanceId
+				continue
+			}
+			id = instanceMember
+		}
+		m := g.members[id]
+		if m == nil {
+			errs[i] = protocol.ErrUnknownMemberId
+			continue
+		}
+		c.removeMember(g, m)
+		left = true
+	}
+	if left {
+		c.membersLeft(g)
+	}
+	return errs
+}
+
+// Offsets returns the offsets committed by all groups
+func (c *Coordinator) Offsets() *OffsetStore {
+	return c.offsets
+}
+
+// CommitOffsets stores the offsets committed by a member of the current generation.
+// Groups without members also accept commits with generation -1 from consumers
+// which assign partitions themselves. Once the commit is validated persist writes
+// it, the offsets are only stored if it succeeds.
+func (c *Coordinator) CommitOffsets(groupID string, generationID int32, memberID, groupInstanceID string, offsets map[string]map[int32]*OffsetAndMetadata, persist func() error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return protocol.ErrCoordinatorNotAvailable
+	}
+	if groupID == "" {
+		return protocol.ErrInvalidGroupId
+	}
+	g := c.groups[groupID]
+	if g == nil {
+		if generationID >= 0 {
+			return protocol.ErrIllegalGeneration
+		}
+		g = newGroup(groupID)
+		c.groups[groupID] = g
+	}
+
+	if generationID >= 0 || g.state != Empty {
+		m := g.members[memberID]
+		if err := g.checkMember(m, memberID, groupInstanceID); err != nil {
+			return err
+		}
+		if generationID != g.generation {
+			return protocol.ErrIllegalGeneration
+		}
+		if g.state == CompletingRebalance {
+			return protocol.ErrRebalanceInProgress
+		}
+		c.heartbeat(g, m)
+	}
+
+	if err := persist(); err != nil {
+		return err
+	}
+	for topic, partitions := range offsets {
+		for partition, offset := range partitions {
+			c.offsets.Commit(groupID, topic, partition, offset)
+		}
+	}
+	return nil
+}
+
+// heartbeat restarts the session timeout of a member
+func (c *Coordinator) heartbeat(g *group, m *member) {
+	m.deadline = time.Now().Add(m.sessionTimeout)
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.timer = time.AfterFunc(m.sessionTimeout, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		// Members waiting for a join response are kept alive until the rebalance times out
+		if c.closed || g.members[m.id] != m || m.join != nil || time.Now().Before(m.deadline) {
+			return
+		}
+		c.removeMember(g, m)
+		c.membersLeft(g)
+	})
+}
+
+func (c *Coordinator) removeMember(g *group, m *member) {
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	if m.join != nil {
+		m.join <- &JoinResult{Err: protocol.ErrUnknownMemberId, GenerationID: -1, MemberID: m.id}
+		m.join = nil
+	}
+	if m.sync != nil {
+		m.sync <- &SyncResult{Err: protocol.ErrUnknownMemberId}
+		m.sync = nil
+	}
+	g.remove(m)
+}
+
+// membersLeft rebalances a group after members left it or their session expired
+func (c *Coordinator) membersLeft(g *group) {
+	switch g.state {
+	case Stable, CompletingRebalance:
+		c.rebalance(g)
+	case PreparingRebalance:
+		c.tryCompleteJoin(g)
+	}
+}
+
+func (c *Coordinator) missingGroupError() error {
+	if c.closed {
+		return protocol.ErrCoordinatorNotAvailable
+	}
+	return protocol.ErrUnknownMemberId
+}
+
+// checkMember validates the member id and group instance id sent by a member
+func (g *group) checkMember(m *member, memberID, groupInstanceID string) error {
+	if groupInstanceID != "" {
+		if id, ok := g.instances[groupInstanceID]; ok && id != memberID {
+			return protocol.ErrFencedInstanceId
+		}
+	}
+	if m == nil {
+		return protocol.ErrUnknownMemberId
+	}
+	return nil
+}
+
+// Close stops the timers of all groups and fails the requests waiting for a rebalance
+func (c *Coordinator) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	for _, g := range c.groups {
+		if g.rebalanceTimer != nil {
+			g.rebalanceTimer.Stop()
+			g.rebalanceTimer = nil
+		}
+		for _, m := range g.members {
+			if m.timer != nil {
+				m.timer.Stop()
+			}
+			if m.join != nil {
+				m.join <- &JoinResult{Err: protocol.ErrCoordinatorNotAvailable, GenerationID: -1, MemberID: m.id}
+				m.join = nil
+			}
+			if m.sync != nil {
+				m.sync <- &SyncResult{Err: protocol.ErrCoordinatorNotAvailable}
+				m.sync = nil
+			}
+		}
+	}
+}
+
+// newMemberID returns a member id made of the client id and a random uuid like Kafka does
+func newMemberID(clientID string) string {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		panic(err)
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%s-%x-%x-%x-%x-%x", clientID, u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
@@ -0,0 +1,387 @@
+package group
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+)
+
+const testTimeout = time.Second
+
+func newTestCoordinator() *Coordinator {
+	return NewCoordinator(Config{MinSessionTimeout: 10 * time.Millisecond})
+}
+
+// joinRequest returns the join request of a consumer supporting the range protocol
+func joinRequest(memberID string) *JoinRequest {
+	return &JoinRequest{
+		GroupID:          "g",
+		MemberID:         memberID,
+		ClientID:         "client",
+		SessionTimeout:   testTimeout,
+		RebalanceTimeout: testTimeout,
+		ProtocolType:     "consumer",
+		Protocols:        []Protocol{{Name: "range", Metadata: []byte(memberID)}},
+	}
+}
+
+func receiveJoin(t *testing.T, ch <-chan *JoinResult) *JoinResult {
+	t.Helper()
+	select {
+	case res := <-ch:
+		return res
+	case <-time.After(testTimeout):
+		t.Fatal("no join response")
+	}
+	return nil
+}
+
+func receiveSync(t *testing.T, ch <-chan *SyncResult) *SyncResult {
+	t.Helper()
+	select {
+	case res := <-ch:
+		return res
+	case <-time.After(testTimeout):
+		t.Fatal("no sync response")
+	}
+	return nil
+}
+
+func checkState(t *testing.T, c *Coordinator, want State) {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if got := c.groups["g"].state; got != want {
+		t.Fatalf("group is %s, want %s", got, want)
+	}
+}
+
+// joinAlone makes a member the only member of a stable group
+func joinAlone(t *testing.T, c *Coordinator) *JoinResult {
+	t.Helper()
+	res := receiveJoin(t, c.Join(joinRequest("")))
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	sync := receiveSync(t, c.Sync(&SyncRequest{
+		GroupID:      "g",
+		GenerationID: res.GenerationID,
+		MemberID:     res.MemberID,
+		Assignments:  map[string][]byte{res.MemberID: []byte("a")},
+	}))
+	if sync.Err != nil {
+		t.Fatal(sync.Err)
+	}
+	return res
+}
+
+func TestJoinSyncHeartbeat(t *testing.T) {
+	c := newTestCoordinator()
+	defer c.Close()
+
+	res := receiveJoin(t, c.Join(joinRequest("")))
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if res.GenerationID != 1 || res.LeaderID != res.MemberID || res.Protocol != "range" || len(res.Members) != 1 {
+		t.Fatalf("joined %+v", res)
+	}
+	checkState(t, c, CompletingRebalance)
+	if err := c.Heartbeat("g", 1, res.MemberID, ""); err != nil {
+		t.Fatalf("heartbeat while completing the rebalance: %v", err)
+	}
+
+	sync := receiveSync(t, c.Sync(&SyncRequest{
+		GroupID:      "g",
+		GenerationID: 1,
+		MemberID:     res.MemberID,
+		Assignments:  map[string][]byte{res.MemberID: []byte("a")},
+	}))
+	if sync.Err != nil || string(sync.Assignment) != "a" {
+		t.Fatalf("synced %+v", sync)
+	}
+	checkState(t, c, Stable)
+
+	if err := c.Heartbeat("g", 1, res.MemberID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Heartbeat("g", 0, res.MemberID, ""); err != protocol.ErrIllegalGeneration {
+		t.Fatalf("heartbeat of an old generation: %v", err)
+	}
+	if err := c.Heartbeat("g", 1, "unknown", ""); err != protocol.ErrUnknownMemberId {
+		t.Fatalf("heartbeat of an unknown member: %v", err)
+	}
+	if sync := receiveSync(t, c.Sync(&SyncRequest{GroupID: "g", GenerationID: 2, MemberID: res.MemberID})); sync.Err != protocol.ErrIllegalGeneration {
+		t.Fatalf("sync of a future generation: %v", sync.Err)
+	}
+}
+
+func TestJoinValidation(t *testing.T) {
+	c := newTestCoordinator()
+	defer c.Close()
+
+	tests := []struct {
+		name   string
+		modify func(req *JoinRequest)
+		want   error
+	}{
+		{"empty group id", func(req *JoinRequest) { req.GroupID = "" }, protocol.ErrInvalidGroupId},
+		{"short session timeout", func(req *JoinRequest) { req.SessionTimeout = time.Millisecond }, protocol.ErrInvalidSessionTimeout},
+		{"long session timeout", func(req *JoinRequest) { req.SessionTimeout = time.Hour }, protocol.ErrInvalidSessionTimeout},
+		{"no protocols", func(req *JoinRequest) { req.Protocols = nil }, protocol.ErrInconsistentGroupProtocol},
+		{"unknown member", func(req *JoinRequest) { req.MemberID = "unknown" }, protocol.ErrUnknownMemberId},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := joinRequest("")
+			tt.modify(req)
+			if res := receiveJoin(t, c.Join(req)); res.Err != tt.want || res.GenerationID != -1 {
+				t.Fatalf("joined %+v, want %v", res, tt.want)
+			}
+		})
+	}
+
+	joinAlone(t, c)
+	req := joinRequest("")
+	req.Protocols = []Protocol{{Name: "roundrobin"}}
+	if res := receiveJoin(t, c.Join(req)); res.Err != protocol.ErrInconsistentGroupProtocol {
+		t.Fatalf("joined without a common protocol: %v", res.Err)
+	}
+}
+
+func TestRebalanceOnJoin(t *testing.T) {
+	c := newTestCoordinator()
+	defer c.Close()
+	leader := joinAlone(t, c)
+
+	// The new member waits until the leader rejoins
+	req := joinRequest("")
+	joining := c.Join(req)
+	checkState(t, c, PreparingRebalance)
+	if err := c.Heartbeat("g", 1, leader.MemberID, ""); err != protocol.ErrRebalanceInProgress {
+		t.Fatalf("heartbeat during the rebalance: %v", err)
+	}
+	select {
+	case res := <-joining:
+		t.Fatalf("joined before the leader rejoined: %+v", res)
+	default:
+	}
+
+	rejoined := receiveJoin(t, c.Join(joinRequest(leader.MemberID)))
+	follower := receiveJoin(t, joining)
+	if rejoined.Err != nil || follower.Err != nil {
+		t.Fatal(rejoined.Err, follower.Err)
+	}
+	if rejoined.GenerationID != 2 || follower.GenerationID != 2 || follower.LeaderID != leader.MemberID {
+		t.Fatalf("joined %+v and %+v", rejoined, follower)
+	}
+	if len(rejoined.Members) != 2 || len(follower.Members) != 0 {
+		t.Fatalf("only the leader gets the members: %+v and %+v", rejoined.Members, follower.Members)
+	}
+
+	// The follower waits for the assignments of the leader
+	waiting := c.Sync(&SyncRequest{GroupID: "g", GenerationID: 2, MemberID: follower.MemberID})
+	select {
+	case res := <-waiting:
+		t.Fatalf("synced before the leader: %+v", res)
+	default:
+	}
+	sync := receiveSync(t, c.Sync(&SyncRequest{
+		GroupID:      "g",
+		GenerationID: 2,
+		MemberID:     leader.MemberID,
+		Assignments:  map[string][]byte{leader.MemberID: []byte("a")},
+	}))
+	if sync.Err != nil || string(sync.Assignment) != "a" {
+		t.Fatalf("leader synced %+v", sync)
+	}
+	// Members without an assignment get an empty one
+	if sync := receiveSync(t, waiting); sync.Err != nil || sync.Assignment == nil || len(sync.Assignment) != 0 {
+		t.Fatalf("follower synced %+v", sync)
+	}
+	checkState(t, c, Stable)
+
+	// A follower rejoining without changes gets the current generation
+	req.MemberID = follower.MemberID
+	if res := receiveJoin(t, c.Join(req)); res.Err != nil || res.GenerationID != 2 {
+		t.Fatalf("follower rejoined %+v", res)
+	}
+	checkState(t, c, Stable)
+}
+
+func TestLeave(t *testing.T) {
+	c := newTestCoordinator()
+	defer c.Close()
+	res := joinAlone(t, c)
+
+	errs := c.Leave("g", []*LeavingMember{{MemberID: res.MemberID}, {MemberID: "unknown"}})
+	if errs[0] != nil || errs[1] != protocol.ErrUnknownMemberId {
+		t.Fatalf("left with %v", errs)
+	}
+	// The last member leaving empties the group, which moves on to a new generation
+	checkState(t, c, Empty)
+	if res := receiveJoin(t, c.Join(joinRequest(""))); res.Err != nil || res.GenerationID != 3 || len(res.Members) != 1 {
+		t.Fatalf("joined %+v", res)
+	}
+}
+
+func TestPendingMemberID(t *testing.T) {
+	c := newTestCoordinator()
+	defer c.Close()
+
+	req := joinRequest("")
+	req.RequireKnownMemberID = true
+	res := receiveJoin(t, c.Join(req))
+	if res.Err != protocol.ErrMemberIdRequired || res.MemberID == "" {
+		t.Fatalf("joined %+v", res)
+	}
+
+	// The member joins with the id it was given
+	req.MemberID = res.MemberID
+	joined := receiveJoin(t, c.Join(req))
+	if joined.Err != nil || joined.MemberID != res.MemberID || joined.GenerationID != 1 {
+		t.Fatalf("rejoined %+v", joined)
+	}
+}
+
+func TestPendingMemberIDExpires(t *testing.T) {
+	c := newTestCoordinator()
+	defer c.Close()
+
+	req := joinRequest("")
+	req.SessionTimeout = 20 * time.Millisecond
+	req.RequireKnownMemberID = true
+	res := receiveJoin(t, c.Join(req))
+	if res.Err != protocol.ErrMemberIdRequired {
+		t.Fatal(res.Err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	req.MemberID = res.MemberID
+	if res := receiveJoin(t, c.Join(req)); res.Err != protocol.ErrUnknownMemberId {
+		t.Fatalf("joined with an expired member id: %+v", res)
+	}
+}
+
+func TestStaticMember(t *testing.T) {
+	c := newTestCoordinator()
+	defer c.Close()
+
+	req := joinRequest("")
+	req.GroupInstanceID = "instance"
+	// Static members get their member id right away
+	req.RequireKnownMemberID = true
+	first := receiveJoin(t, c.Join(req))
+	if first.Err != nil {
+		t.Fatal(first.Err)
+	}
+	sync := receiveSync(t, c.Sync(&SyncRequest{
+		GroupID:         "g",
+		GenerationID:    first.GenerationID,
+		MemberID:        first.MemberID,
+		GroupInstanceID: "instance",
+		Assignments:     map[string][]byte{first.MemberID: []byte("a")},
+	}))
+	if sync.Err != nil {
+		t.Fatal(sync.Err)
+	}
+
+	// The restarted instance takes over the membership and fences the old member id
+	restarted := receiveJoin(t, c.Join(req))
+	if restarted.Err != nil || restarted.MemberID == first.MemberID || restarted.LeaderID != restarted.MemberID {
+		t.Fatalf("restarted %+v", restarted)
+	}
+	if err := c.Heartbeat("g", restarted.GenerationID, first.MemberID, "instance"); err != protocol.ErrFencedInstanceId {
+		t.Fatalf("heartbeat of the old member id: %v", err)
+	}
+	if errs := c.Leave("g", []*LeavingMember{{MemberID: first.MemberID, GroupInstanceID: "instance"}}); errs[0] != protocol.ErrFencedInstanceId {
+		t.Fatalf("old member id left with %v", errs[0])
+	}
+	if errs := c.Leave("g", []*LeavingMember{{GroupInstanceID: "instance"}}); errs[0] != nil {
+		t.Fatalf("instance left with %v", errs[0])
+	}
+	if errs := c.Leave("g", []*LeavingMember{{GroupInstanceID: "instance"}}); errs[0] != protocol.ErrUnknownMemberId {
+		t.Fatalf("instance left twice with %v", errs[0])
+	}
+}
+
+func TestSessionTimeout(t *testing.T) {
+	c := newTestCoordinator()
+	defer c.Close()
+	leader := joinAlone(t, c)
+
+	req := joinRequest("")
+	req.SessionTimeout = 50 * time.Millisecond
+	joining := c.Join(req)
+	rejoined := receiveJoin(t, c.Join(joinRequest(leader.MemberID)))
+	follower := receiveJoin(t, joining)
+	if rejoined.Err != nil || follower.Err != nil {
+		t.Fatal(rejoined.Err, follower.Err)
+	}
+
+	// The follower never syncs nor sends heartbeats, so its session expires and
+	// the leader has to rejoin without it
+	deadline := time.Now().Add(testTimeout)
+	for {
+		err := c.Heartbeat("g", rejoined.GenerationID, leader.MemberID, "")
+		if err == protocol.ErrRebalanceInProgress {
+			break
+		}
+		if err != nil || time.Now().After(deadline) {
+			t.Fatalf("heartbeat after the session of the follower expired: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := c.Heartbeat("g", rejoined.GenerationID, follower.MemberID, ""); err != protocol.ErrUnknownMemberId {
+		t.Fatalf("heartbeat of the expired member: %v", err)
+	}
+	res := receiveJoin(t, c.Join(joinRequest(leader.MemberID)))
+	if res.Err != nil || res.GenerationID != 3 || len(res.Members) != 1 {
+		t.Fatalf("rejoined %+v", res)
+	}
+}
+
+func TestCommitOffsets(t *testing.T) {
+	c := newTestCoordinator()
+	defer c.Close()
+	res := joinAlone(t, c)
+
+	offsets := map[string]map[int32]*OffsetAndMetadata{"t": {0: {Offset: 5}}}
+	persisted := 0
+	persist := func() error {
+		persisted++
+		return nil
+	}
+	if err := c.CommitOffsets("g", res.GenerationID+1, res.MemberID, "", offsets, persist); err != protocol.ErrIllegalGeneration {
+		t.Fatalf("committed for a future generation: %v", err)
+	}
+	if err := c.CommitOffsets("g", -1, "", "", offsets, persist); err != protocol.ErrUnknownMemberId {
+		t.Fatalf("committed without a member to a group with members: %v", err)
+	}
+	if persisted != 0 {
+		t.Fatalf("persisted %d rejected commits", persisted)
+	}
+
+	// Offsets which failed to persist are not stored
+	failed := errors.New("failed")
+	if err := c.CommitOffsets("g", res.GenerationID, res.MemberID, "", offsets, func() error { return failed }); err != failed {
+		t.Fatal(err)
+	}
+	if _, ok := c.Offsets().Fetch("g", "t", 0); ok {
+		t.Fatal("stored offsets which failed to persist")
+	}
+
+	if err := c.CommitOffsets("g", res.GenerationID, res.MemberID, "", offsets, persist); err != nil {
+		t.Fatal(err)
+	}
+	if o, ok := c.Offsets().Fetch("g", "t", 0); !ok || o.Offset != 5 || persisted != 1 {
+		t.Fatalf("fetched %+v after %d commits", o, persisted)
+	}
+
+	// Groups without members accept commits from consumers assigning partitions themselves
+	if err := c.CommitOffsets("manual", -1, "", "", offsets, persist); err != nil {
+		t.Fatal(err)
+	}
+}
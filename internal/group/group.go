@@ -0,0 +1,247 @@
+package group
+
+import (
+	"bytes"
+	"time"
+)
+
+// State is the state of a group in the rebalance protocol
+type State int
+
+const (
+	// Empty groups have no members but may still have committed offsets
+	Empty State = iota
+	// PreparingRebalance groups wait for their members to rejoin
+	PreparingRebalance
+	// CompletingRebalance groups wait for the leader to sync the assignments
+	CompletingRebalance
+	// Stable groups have a generation whose assignments are known to all members
+	Stable
+)
+
+func (s State) String() string {
+	switch s {
+	case Empty:
+		return "Empty"
+	case PreparingRebalance:
+		return "PreparingRebalance"
+	case CompletingRebalance:
+		return "CompletingRebalance"
+	case Stable:
+		return "Stable"
+	}
+	return "Unknown"
+}
+
+// Protocol is an assignment protocol supported by a member, like range or roundrobin
+type Protocol struct {
+	Name     string
+	Metadata []byte
+}
+
+type member struct {
+	id               string
+	instanceID       string
+	clientID         string
+	clientHost       string
+	sessionTimeout   time.Duration
+	rebalanceTimeout time.Duration
+	protocolType     string
+	protocols        []Protocol
+	assignment       []byte
+
+	// join and sync are set while the member waits for a join or sync response
+	join chan *JoinResult
+	sync chan *SyncResult
+
+	deadline time.Time
+	timer    *time.Timer
+}
+
+func (m *member) supports(name string) bool {
+	for _, p := range m.protocols {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *member) metadata(name string) []byte {
+	for _, p := range m.protocols {
+		if p.Name == name {
+			return p.Metadata
+		}
+	}
+	return nil
+}
+
+func (m *member) sameProtocols(protocols []Protocol) bool {
+	if len(m.protocols) != len(protocols) {
+		return false
+	}
+	for i, p := range m.protocols {
+		if p.Name != protocols[i].Name || !bytes.Equal(p.Metadata, protocols[i].Metadata) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *member) update(req *JoinRequest) {
+	m.clientID = req.ClientID
+	m.clientHost = req.ClientHost
+	m.sessionTimeout = req.SessionTimeout
+	m.rebalanceTimeout = req.RebalanceTimeout
+	m.protocolType = req.ProtocolType
+	m.protocols = req.Protocols
+}
+
+type group struct {
+	id           string
+	state        State
+	protocolType string
+	protocol     string
+	generation   int32
+	leader       string
+
+	members map[string]*member
+	// order keeps the member ids in join order, the first one becomes the leader
+	order []string
+	// instances maps the group instance ids of static members to their member ids
+	instances map[string]string
+	// pending holds the member ids handed out with a member id required error
+	pending map[string]struct{}
+
+	rebalanceTimer *time.Timer
+	initialDelay   bool
+}
+
+func newGroup(id string) *group {
+	return &group{
+		id:        id,
+		members:   make(map[string]*member),
+		instances: make(map[string]string),
+		pending:   make(map[string]struct{}),
+	}
+}
+
+// supports reports whether a joining member can be part of the group, other
+// than m it must share the protocol type and one protocol with every member
+func (g *group) supports(req *JoinRequest, m *member) bool {
+	others := 0
+	for _, other := range g.members {
+		if other != m {
+			others++
+		}
+	}
+	if others == 0 {
+		return true
+	}
+	if req.ProtocolType != g.protocolType {
+		return false
+	}
+	for _, p := range req.Protocols {
+		supported := true
+		for _, other := range g.members {
+			if other != m && !other.supports(p.Name) {
+				supported = false
+				break
+			}
+		}
+		if supported {
+			return true
+		}
+	}
+	return false
+}
+
+// selectProtocol picks the protocol supported by all members which most members prefer
+func (g *group) selectProtocol() string {
+	var candidates []string
+	for _, p := range g.members[g.order[0]].protocols {
+		supported := true
+		for _, m := range g.members {
+			if !m.supports(p.Name) {
+				supported = false
+				break
+			}
+		}
+		if supported {
+			candidates = append(candidates, p.Name)
+		}
+	}
+
+	votes := make(map[string]int)
+	for _, m := range g.members {
+		for _, p := range m.protocols {
+			if contains(candidates, p.Name) {
+				votes[p.Name]++
+				break
+			}
+		}
+	}
+	selected := ""
+	for _, name := range candidates {
+		if selected == "" || votes[name] > votes[selected] {
+			selected = name
+		}
+	}
+	return selected
+}
+
+// rebalanceTimeout is the longest rebalance timeout of the members
+func (g *group) rebalanceTimeout() time.Duration {
+	var timeout time.Duration
+	for _, m := range g.members {
+		if m.rebalanceTimeout > timeout {
+			timeout = m.rebalanceTimeout
+		}
+	}
+	return timeout
+}
+
+func (g *group) joinResult(m *member) *JoinResult {
+	res := &JoinResult{
+		GenerationID: g.generation,
+		Protocol:     g.protocol,
+		LeaderID:     g.leader,
+		MemberID:     m.id,
+	}
+	if m.id == g.leader {
+		for _, id := range g.order {
+			other := g.members[id]
+			res.Members = append(res.Members, &MemberMetadata{
+				MemberID:        other.id,
+				GroupInstanceID: other.instanceID,
+				Metadata:        other.metadata(g.protocol),
+			})
+		}
+	}
+	return res
+}
+
+func (g *group) remove(m *member) {
+	delete(g.members, m.id)
+	for i, id := range g.order {
+		if id == m.id {
+			g.order = append(g.order[:i:i], g.order[i+1:]...)
+			break
+		}
+	}
+	if m.instanceID != "" && g.instances[m.instanceID] == m.id {
+		delete(g.instances, m.instanceID)
+	}
+	if g.leader == m.id {
+		g.leader = ""
+	}
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package group
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OffsetsTopic is the internal topic committed offsets are written to
+const OffsetsTopic = "__consumer_offsets"
+
+// OffsetsTopicPartitions is the partition count of the offsets topic, the default
+// of offsets.topic.num.partitions
+const OffsetsTopicPartitions = 50
+
+// OffsetAndMetadata is an offset committed by a group for a topic partition
+type OffsetAndMetadata struct {
+	Offset      int64
+	LeaderEpoch int32
+	Metadata    string
+	CommitTime  time.Time
+	// ExpireTime is set when the commit asked for a retention time
+	ExpireTime time.Time
+}
+
+func (o *OffsetAndMetadata) expired(now time.Time) bool {
+	return !o.ExpireTime.IsZero() && !now.Before(o.ExpireTime)
+}
+
+// OffsetStore holds the committed offsets by group, topic and partition
+type OffsetStore struct {
+	mu      sync.RWMutex
+	offsets map[string]map[string]map[int32]*OffsetAndMetadata
+	// pending holds the offsets committed within transactions by producer ID
+	pending map[int64][]*pendingOffset
+}
+
+// pendingOffset is an offset committed within a transaction which is not complete yet
+type pendingOffset struct {
+	group     string
+	topic     string
+	partition int32
+	offset    *OffsetAndMetadata
+}
+
+func NewOffsetStore() *OffsetStore {
+	return &OffsetStore{
+		offsets: make(map[string]map[string]map[int32]*OffsetAndMetadata),
+		pending: make(map[int64][]*pendingOffset),
+	}
+}
+
+// Commit stores the offset of a group for a topic partition
+func (s *OffsetStore) Commit(group, topic string, partition int32, offset *OffsetAndMetadata) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.offsets[group] == nil {
+		s.offsets[group] = make(map[string]map[int32]*OffsetAndMetadata)
+	}
+	if s.offsets[group][topic] == nil {
+		s.offsets[group][topic] = make(map[int32]*OffsetAndMetadata)
+	}
+	s.offsets[group][topic][partition] = offset
+}
+
+// CommitPending stores the offset of a group committed within the transaction of a
+// producer. It is only visible once the transaction commits.
+func (s *OffsetStore) CommitPending(producerID int64, group, topic string, partition int32, offset *OffsetAndMetadata) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[producerID] = append(s.pending[producerID], &pendingOffset{
+		group:     group,
+		topic:     topic,
+		partition: partition,
+		offset:    offset,
+	})
+}
+
+// CompleteTransaction applies the offsets committed within the transaction of a
+// producer if it commits, or drops them if it aborts
+func (s *OffsetStore) CompleteTransaction(producerID int64, commit bool) {
+	s.mu.Lock()
+	pending := s.pending[producerID]
+	delete(s.pending, producerID)
+	s.mu.Unlock()
+
+	if !commit {
+		return
+	}
+	for _, p := range pending {
+		s.Commit(p.group, p.topic, p.partition, p.offset)
+	}
+}
+
+// Fetch returns the offset committed by a group for a topic partition, if it has not expired
+func (s *OffsetStore) Fetch(group, topic string, partition int32) (*OffsetAndMetadata, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	offset, ok := s.offsets[group][topic][partition]
+	if !ok || offset.expired(time.Now()) {
+		return nil, false
+	}
+	return offset, true
+}
+
+// Topics returns the sorted topics a group has committed offsets for
+func (s *OffsetStore) Topics(group string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	topics := make([]string, 0, len(s.offsets[group]))
+	for topic := range s.offsets[group] {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Partitions returns the sorted partitions of a topic a group has committed offsets for
+func (s *OffsetStore) Partitions(group, topic string) []int32 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := time.Now()
+	partitions := make([]int32, 0, len(s.offsets[group][topic]))
+	for partition, offset := range s.offsets[group][topic] {
+		if !offset.expired(now) {
+			partitions = append(partitions, partition)
+		}
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+	return partitions
+}
+
+// DeleteTopic removes the offsets every group committed for a deleted topic
+func (s *OffsetStore) DeleteTopic(topic string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, topics := range s.offsets {
+		delete(topics, topic)
+	}
+}
+
+// OffsetCommitKey encodes the key of an offset commit in the offsets topic
+func OffsetCommitKey(group, topic string, partition int32) []byte {
+	buf := &bytes.Buffer{}
+	writeInt16(buf, 1)
+	writeString(buf, group)
+	writeString(buf, topic)
+	writeInt32(buf, partition)
+	return buf.Bytes()
+}
+
+// OffsetCommitValue encodes the value of an offset commit in the offsets topic. Like
+// Kafka it uses version 1 of the schema when the offset expires and version 3 otherwise.
+func OffsetCommitValue(offset *OffsetAndMetadata) []byte {
+	buf := &bytes.Buffer{}
+	if !offset.ExpireTime.IsZero() {
+		writeInt16(buf, 1)
+		writeInt64(buf, offset.Offset)
+		writeString(buf, offset.Metadata)
+		writeInt64(buf, millis(offset.CommitTime))
+		writeInt64(buf, millis(offset.ExpireTime))
+		return buf.Bytes()
+	}
+	writeInt16(buf, 3)
+	writeInt64(buf, offset.Offset)
+	writeInt32(buf, offset.LeaderEpoch)
+	writeString(buf, offset.Metadata)
+	writeInt64(buf, millis(offset.CommitTime))
+	return buf.Bytes()
+}
+
+// OffsetsPartition returns the partition of the offsets topic a group commits to
+func OffsetsPartition(group string, partitions int32) int32 {
+	// Java's String.hashCode, as used by Kafka
+	var hash int32
+	for _, r := range group {
+		hash = 31*hash + int32(r)
+	}
+	if hash < 0 {
+		hash = -hash
+		if hash < 0 {
+			hash = 0
+		}
+	}
+	return hash % partitions
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func writeInt16(buf *bytes.Buffer, v int16) {
+	binary.Write(buf, binary.BigEndian, v)
+}
+
+func writeInt32(buf *bytes.Buffer, v int32) {
+	binary.Write(buf, binary.BigEndian, v)
+}
+
+func writeInt64(buf *bytes.Buffer, v int64) {
+	binary.Write(buf, binary.BigEndian, v)
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	writeInt16(buf, int16(len(s)))
+	buf.WriteString(s)
+}
@@ -0,0 +1,31 @@
+package protocol
+
+import "time"
+
+type AddOffsetsToTxnResponse struct {
+	APIVersion int16
+
+	ThrottleTime time.Duration
+	ErrorCode    int16
+}
+
+func (r *AddOffsetsToTxnResponse) Encode(e PacketEncoder) (err error) {
+	e.PutInt32(int32(r.ThrottleTime / time.Millisecond))
+	e.PutInt16(r.ErrorCode)
+	return nil
+}
+
+func (r *AddOffsetsToTxnResponse) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	throttle, err := d.Int32()
+	if err != nil {
+		return err
+	}
+	r.ThrottleTime = time.Duration(throttle) * time.Millisecond
+	r.ErrorCode, err = d.Int16()
+	return err
+}
+
+func (r *AddOffsetsToTxnResponse) Version() int16 {
+	return r.APIVersion
+}
@@ -0,0 +1,66 @@
+package protocol
+
+type AddPartitionsToTxnRequest struct {
+	APIVersion int16
+
+	TransactionalID string
+	ProducerID      int64
+	ProducerEpoch   int16
+	Topics          map[string][]int32
+}
+
+func (r *AddPartitionsToTxnRequest) Encode(e PacketEncoder) (err error) {
+	if err = e.PutString(r.TransactionalID); err != nil {
+		return err
+	}
+	e.PutInt64(r.ProducerID)
+	e.PutInt16(r.ProducerEpoch)
+	if err = e.PutArrayLength(len(r.Topics)); err != nil {
+		return err
+	}
+	for topic, partitions := range r.Topics {
+		if err = e.PutString(topic); err != nil {
+			return err
+		}
+		if err = e.PutInt32Array(partitions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *AddPartitionsToTxnRequest) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	if r.TransactionalID, err = d.String(); err != nil {
+		return err
+	}
+	if r.ProducerID, err = d.Int64(); err != nil {
+		return err
+	}
+	if r.ProducerEpoch, err = d.Int16(); err != nil {
+		return err
+	}
+	n, err := d.ArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Topics = make(map[string][]int32, n)
+	for i := 0; i < n; i++ {
+		topic, err := d.String()
+		if err != nil {
+			return err
+		}
+		if r.Topics[topic], err = d.Int32Array(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *AddPartitionsToTxnRequest) Key() int16 {
+	return AddPartitionsToTxnKey
+}
+
+func (r *AddPartitionsToTxnRequest) Version() int16 {
+	return r.APIVersion
+}
@@ -0,0 +1,81 @@
+package protocol
+
+import "time"
+
+type AddPartitionsToTxnPartitionResult struct {
+	Partition int32
+	ErrorCode int16
+}
+
+type AddPartitionsToTxnTopicResult struct {
+	Name    string
+	Results []*AddPartitionsToTxnPartitionResult
+}
+
+type AddPartitionsToTxnResponse struct {
+	APIVersion int16
+
+	ThrottleTime time.Duration
+	Results      []*AddPartitionsToTxnTopicResult
+}
+
+func (r *AddPartitionsToTxnResponse) Encode(e PacketEncoder) (err error) {
+	e.PutInt32(int32(r.ThrottleTime / time.Millisecond))
+	if err = e.PutArrayLength(len(r.Results)); err != nil {
+		return err
+	}
+	for _, t := range r.Results {
+		if err = e.PutString(t.Name); err != nil {
+			return err
+		}
+		if err = e.PutArrayLength(len(t.Results)); err != nil {
+			return err
+		}
+		for _, p := range t.Results {
+			e.PutInt32(p.Partition)
+			e.PutInt16(p.ErrorCode)
+		}
+	}
+	return nil
+}
+
+func (r *AddPartitionsToTxnResponse) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	throttle, err := d.Int32()
+	if err != nil {
+		return err
+	}
+	r.ThrottleTime = time.Duration(throttle) * time.Millisecond
+	n, err := d.ArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Results = make([]*AddPartitionsToTxnTopicResult, n)
+	for i := range r.Results {
+		t := &AddPartitionsToTxnTopicResult{}
+		if t.Name, err = d.String(); err != nil {
+			return err
+		}
+		pn, err := d.ArrayLength()
+		if err != nil {
+			return err
+		}
+		t.Results = make([]*AddPartitionsToTxnPartitionResult, pn)
+		for j := range t.Results {
+			p := &AddPartitionsToTxnPartitionResult{}
+			if p.Partition, err = d.Int32(); err != nil {
+				return err
+			}
+			if p.ErrorCode, err = d.Int16(); err != nil {
+				return err
+			}
+			t.Results[j] = p
+		}
+		r.Results[i] = t
+	}
+	return nil
+}
+
+func (r *AddPartitionsToTxnResponse) Version() int16 {
+	return r.APIVersion
+}
@@ -1,48 +1,173 @@
 package protocol
 
+import "strings"
+
 // Protocol API keys. See: https://kafka.apache.org/protocol#protocol_api_keys
 const (
-	ProduceKey                 = 0
-	FetchKey                   = 1
-	OffsetsKey                 = 2
-	MetadataKey                = 3
-	LeaderAndISRKey            = 4
-	StopReplicaKey             = 5
-	UpdateMetadataKey          = 6
-	ControlledShutdownKey      = 7
-	OffsetCommitKey            = 8
-	OffsetFetchKey             = 9
-	FindCoordinatorKey         = 10
-	JoinGroupKey               = 11
-	HeartbeatKey               = 12
-	LeaveGroupKey              = 13
-	SyncGroupKey               = 14
-	DescribeGroupsKey          = 15
-	ListGroupsKey              = 16
-	SaslHandshakeKey           = 17
-	APIVersionsKey             = 18
-	CreateTopicsKey            = 19
-	DeleteTopicsKey            = 20
-	DeleteRecordsKey           = 21
-	InitProducerIDKey          = 22
-	OffsetForLeaderEpochKey    = 23
-	AddPartitionsToTxnKey      = 24
-	AddOffsetsToTxnKey         = 25
-	EndTxnKey                  = 26
-	WriteTxnMarkersKey         = 27
-	TxnOffsetCommitKey         = 28
-	DescribeAclsKey            = 29
-	CreateAclsKey              = 30
-	DeleteAclsKey              = 31
-	DescribeConfigsKey         = 32
-	AlterConfigsKey            = 33
-	AlterReplicaLogDirsKey     = 34
-	DescribeLogDirsKey         = 35
-	SaslAuthenticateKey        = 36
-	CreatePartitionsKey        = 37
-	CreateDelegationTokenKey   = 38
-	RenewDelegationTokenKey    = 39
-	ExpireDelegationTokenKey   = 40
-	DescribeDelegationTokenKey = 41
-	DeleteGroupsKey            = 42
+	ProduceKey                      = 0
+	FetchKey                        = 1
+	OffsetsKey                      = 2
+	MetadataKey                     = 3
+	LeaderAndISRKey                 = 4
+	StopReplicaKey                  = 5
+	UpdateMetadataKey               = 6
+	ControlledShutdownKey           = 7
+	OffsetCommitKey                 = 8
+	OffsetFetchKey                  = 9
+	FindCoordinatorKey              = 10
+	JoinGroupKey                    = 11
+	HeartbeatKey                    = 12
+	LeaveGroupKey                   = 13
+	SyncGroupKey                    = 14
+	DescribeGroupsKey               = 15
+	ListGroupsKey                   = 16
+	SaslHandshakeKey                = 17
+	APIVersionsKey                  = 18
+	CreateTopicsKey                 = 19
+	DeleteTopicsKey                 = 20
+	DeleteRecordsKey                = 21
+	InitProducerIDKey               = 22
+	OffsetForLeaderEpochKey         = 23
+	AddPartitionsToTxnKey           = 24
+	AddOffsetsToTxnKey              = 25
+	EndTxnKey                       = 26
+	WriteTxnMarkersKey              = 27
+	TxnOffsetCommitKey              = 28
+	DescribeAclsKey                 = 29
+	CreateAclsKey                   = 30
+	DeleteAclsKey                   = 31
+	DescribeConfigsKey              = 32
+	AlterConfigsKey                 = 33
+	AlterReplicaLogDirsKey          = 34
+	DescribeLogDirsKey              = 35
+	SaslAuthenticateKey             = 36
+	CreatePartitionsKey             = 37
+	CreateDelegationTokenKey        = 38
+	RenewDelegationTokenKey         = 39
+	ExpireDelegationTokenKey        = 40
+	DescribeDelegationTokenKey      = 41
+	DeleteGroupsKey                 = 42
+	ElectLeadersKey                 = 43
+	IncrementalAlterConfigsKey      = 44
+	AlterPartitionReassignmentsKey  = 45
+	ListPartitionReassignmentsKey   = 46
+	OffsetDeleteKey                 = 47
+	DescribeClientQuotasKey         = 48
+	AlterClientQuotasKey            = 49
+	DescribeUserScramCredentialsKey = 50
+	AlterUserScramCredentialsKey    = 51
+	VoteKey                         = 52
+	BeginQuorumEpochKey             = 53
+	EndQuorumEpochKey               = 54
+	DescribeQuorumKey               = 55
+	AlterPartitionKey               = 56
+	UpdateFeaturesKey               = 57
+	EnvelopeKey                     = 58
+	FetchSnapshotKey                = 59
+	DescribeClusterKey              = 60
+	DescribeProducersKey            = 61
+	BrokerRegistrationKey           = 62
+	BrokerHeartbeatKey              = 63
+	UnregisterBrokerKey             = 64
+	DescribeTransactionsKey         = 65
+	ListTransactionsKey             = 66
+	AllocateProducerIDsKey          = 67
+	ConsumerGroupHeartbeatKey       = 68
+	ConsumerGroupDescribeKey        = 69
+	ControllerRegistrationKey       = 70
+	GetTelemetrySubscriptionsKey    = 71
+	PushTelemetryKey                = 72
+	AssignReplicasToDirsKey         = 73
+	ListClientMetricsResourcesKey   = 74
+	DescribeTopicPartitionsKey      = 75
 )
+
+// apiNames are the names of the APIs by key, as in the Kafka protocol guide
+var apiNames = map[int16]string{
+	ProduceKey:                      "Produce",
+	FetchKey:                        "Fetch",
+	OffsetsKey:                      "ListOffsets",
+	MetadataKey:                     "Metadata",
+	LeaderAndISRKey:                 "LeaderAndIsr",
+	StopReplicaKey:                  "StopReplica",
+	UpdateMetadataKey:               "UpdateMetadata",
+	ControlledShutdownKey:           "ControlledShutdown",
+	OffsetCommitKey:                 "OffsetCommit",
+	OffsetFetchKey:                  "OffsetFetch",
+	FindCoordinatorKey:              "FindCoordinator",
+	JoinGroupKey:                    "JoinGroup",
+	HeartbeatKey:                    "Heartbeat",
+	LeaveGroupKey:                   "LeaveGroup",
+	SyncGroupKey:                    "SyncGroup",
+	DescribeGroupsKey:               "DescribeGroups",
+	ListGroupsKey:                   "ListGroups",
+	SaslHandshakeKey:                "SaslHandshake",
+	APIVersionsKey:                  "ApiVersions",
+	CreateTopicsKey:                 "CreateTopics",
+	DeleteTopicsKey:                 "DeleteTopics",
+	DeleteRecordsKey:                "DeleteRecords",
+	InitProducerIDKey:               "InitProducerId",
+	OffsetForLeaderEpochKey:         "OffsetForLeaderEpoch",
+	AddPartitionsToTxnKey:           "AddPartitionsToTxn",
+	AddOffsetsToTxnKey:              "AddOffsetsToTxn",
+	EndTxnKey:                       "EndTxn",
+	WriteTxnMarkersKey:              "WriteTxnMarkers",
+	TxnOffsetCommitKey:              "TxnOffsetCommit",
+	DescribeAclsKey:                 "DescribeAcls",
+	CreateAclsKey:                   "CreateAcls",
+	DeleteAclsKey:                   "DeleteAcls",
+	DescribeConfigsKey:              "DescribeConfigs",
+	AlterConfigsKey:                 "AlterConfigs",
+	AlterReplicaLogDirsKey:          "AlterReplicaLogDirs",
+	DescribeLogDirsKey:              "DescribeLogDirs",
+	SaslAuthenticateKey:             "SaslAuthenticate",
+	CreatePartitionsKey:             "CreatePartitions",
+	CreateDelegationTokenKey:        "CreateDelegationToken",
+	RenewDelegationTokenKey:         "RenewDelegationToken",
+	ExpireDelegationTokenKey:        "ExpireDelegationToken",
+	DescribeDelegationTokenKey:      "DescribeDelegationToken",
+	DeleteGroupsKey:                 "DeleteGroups",
+	ElectLeadersKey:                 "ElectLeaders",
+	IncrementalAlterConfigsKey:      "IncrementalAlterConfigs",
+	AlterPartitionReassignmentsKey:  "AlterPartitionReassignments",
+	ListPartitionReassignmentsKey:   "ListPartitionReassignments",
+	OffsetDeleteKey:                 "OffsetDelete",
+	DescribeClientQuotasKey:         "DescribeClientQuotas",
+	AlterClientQuotasKey:            "AlterClientQuotas",
+	DescribeUserScramCredentialsKey: "DescribeUserScramCredentials",
+	AlterUserScramCredentialsKey:    "AlterUserScramCredentials",
+	VoteKey:                         "Vote",
+	BeginQuorumEpochKey:             "BeginQuorumEpoch",
+	EndQuorumEpochKey:               "EndQuorumEpoch",
+	DescribeQuorumKey:               "DescribeQuorum",
+	AlterPartitionKey:               "AlterPartition",
+	UpdateFeaturesKey:               "UpdateFeatures",
+	EnvelopeKey:                     "Envelope",
+	FetchSnapshotKey:                "FetchSnapshot",
+	DescribeClusterKey:              "DescribeCluster",
+	DescribeProducersKey:            "DescribeProducers",
+	BrokerRegistrationKey:           "BrokerRegistration",
+	BrokerHeartbeatKey:              "BrokerHeartbeat",
+	UnregisterBrokerKey:             "UnregisterBroker",
+	DescribeTransactionsKey:         "DescribeTransactions",
+	ListTransactionsKey:             "ListTransactions",
+	AllocateProducerIDsKey:          "AllocateProducerIds",
+	ConsumerGroupHeartbeatKey:       "ConsumerGroupHeartbeat",
+	ConsumerGroupDescribeKey:        "ConsumerGroupDescribe",
+	ControllerRegistrationKey:       "ControllerRegistration",
+	GetTelemetrySubscriptionsKey:    "GetTelemetrySubscriptions",
+	PushTelemetryKey:                "PushTelemetry",
+	AssignReplicasToDirsKey:         "AssignReplicasToDirs",
+	ListClientMetricsResourcesKey:   "ListClientMetricsResources",
+	DescribeTopicPartitionsKey:      "DescribeTopicPartitions",
+}
+
+// APIKeyByName returns the key of an API from its name, like ListOffsets, ignoring case
+func APIKeyByName(name string) (int16, bool) {
+	for key, n := range apiNames {
+		if strings.EqualFold(n, name) {
+			return key, true
+		}
+	}
+	return 0, false
+}
@@ -2,15 +2,38 @@ package protocol
 
 type APIVersionsRequest struct {
 	APIVersion int16
+
+	// ClientSoftwareName and ClientSoftwareVersion are sent from version 3 on
+	ClientSoftwareName    string
+	ClientSoftwareVersion string
 }
 
-func (c *APIVersionsRequest) Encode(_ PacketEncoder) error {
-	return nil
+func (c *APIVersionsRequest) Encode(e PacketEncoder) (err error) {
+	if c.APIVersion < 3 {
+		return nil
+	}
+	if err = e.PutCompactString(c.ClientSoftwareName); err != nil {
+		return err
+	}
+	if err = e.PutCompactString(c.ClientSoftwareVersion); err != nil {
+		return err
+	}
+	return e.PutTaggedFields(nil)
 }
 
-func (c *APIVersionsRequest) Decode(_ PacketDecoder, version int16) error {
+func (c *APIVersionsRequest) Decode(d PacketDecoder, version int16) (err error) {
 	c.APIVersion = version
-	return nil
+	if version < 3 {
+		return nil
+	}
+	if c.ClientSoftwareName, err = d.CompactString(); err != nil {
+		return err
+	}
+	if c.ClientSoftwareVersion, err = d.CompactString(); err != nil {
+		return err
+	}
+	_, err = d.TaggedFields()
+	return err
 }
 
 func (c *APIVersionsRequest) Key() int16 {
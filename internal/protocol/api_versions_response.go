@@ -17,25 +17,33 @@ type APIVersion struct {
 }
 
 func (c *APIVersionsResponse) Encode(e PacketEncoder) error {
+	flexible := c.APIVersion >= 3
 	e.PutInt16(c.ErrorCode)
 
-	if err := e.PutArrayLength(len(c.APIVersions)); err != nil {
+	if err := putArrayLength(e, len(c.APIVersions), flexible); err != nil {
 		return err
 	}
 	for _, av := range c.APIVersions {
 		e.PutInt16(av.APIKey)
 		e.PutInt16(av.MinVersion)
 		e.PutInt16(av.MaxVersion)
+		if err := putTaggedFields(e, flexible); err != nil {
+			return err
+		}
 	}
 	if c.APIVersion >= 1 {
 		e.PutInt32(int32(c.ThrottleTime / time.Millisecond))
 	}
-	return nil
+	return putTaggedFields(e, flexible)
 }
 
-func (c *APIVersionsResponse) Decode(d PacketDecoder, version int16) error {
+func (c *APIVersionsResponse) Decode(d PacketDecoder, version int16) (err error) {
 	c.APIVersion = version
-	l, err := d.ArrayLength()
+	flexible := version >= 3
+	if c.ErrorCode, err = d.Int16(); err != nil {
+		return err
+	}
+	l, err := getArrayLength(d, flexible)
 	if err != nil {
 		return err
 	}
@@ -55,6 +63,9 @@ func (c *APIVersionsResponse) Decode(d PacketDecoder, version int16) error {
 		if err != nil {
 			return err
 		}
+		if err = skipTaggedFields(d, flexible); err != nil {
+			return err
+		}
 
 		c.APIVersions[i] = APIVersion{
 			APIKey:     key,
@@ -69,7 +80,7 @@ func (c *APIVersionsResponse) Decode(d PacketDecoder, version int16) error {
 		}
 		c.ThrottleTime = time.Duration(throttle) * time.Millisecond
 	}
-	return nil
+	return skipTaggedFields(d, flexible)
 }
 
 func (r *APIVersionsResponse) Version() int16 {
@@ -0,0 +1,95 @@
+package protocol
+
+import "time"
+
+type CreatePartitionsTopic struct {
+	Name  string
+	Count int32
+	// Assignments holds the replicas of every new partition, it is null to let
+	// the broker assign them
+	Assignments [][]int32
+}
+
+type CreatePartitionsRequest struct {
+	APIVersion int16
+
+	Topics       []*CreatePartitionsTopic
+	Timeout      time.Duration
+	ValidateOnly bool
+}
+
+func (r *CreatePartitionsRequest) Encode(e PacketEncoder) (err error) {
+	if err = e.PutArrayLength(len(r.Topics)); err != nil {
+		return err
+	}
+	for _, t := range r.Topics {
+		if err = e.PutString(t.Name); err != nil {
+			return err
+		}
+		e.PutInt32(t.Count)
+		if t.Assignments == nil {
+			e.PutInt32(-1)
+			continue
+		}
+		if err = e.PutArrayLength(len(t.Assignments)); err != nil {
+			return err
+		}
+		for _, brokerIDs := range t.Assignments {
+			if err = e.PutInt32Array(brokerIDs); err != nil {
+				return err
+			}
+		}
+	}
+	e.PutInt32(int32(r.Timeout / time.Millisecond))
+	e.PutBool(r.ValidateOnly)
+	return nil
+}
+
+func (r *CreatePartitionsRequest) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	n, err := d.ArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Topics = make([]*CreatePartitionsTopic, n)
+	for i := range r.Topics {
+		t := &CreatePartitionsTopic{}
+		if t.Name, err = d.String(); err != nil {
+			return err
+		}
+		if t.Count, err = d.Int32(); err != nil {
+			return err
+		}
+		// The assignments array is nullable, which ArrayLength does not support
+		an, err := d.Int32()
+		if err != nil {
+			return err
+		}
+		if an >= 0 {
+			t.Assignments = [][]int32{}
+		}
+		for j := int32(0); j < an; j++ {
+			brokerIDs, err := d.Int32Array()
+			if err != nil {
+				return err
+			}
+			t.Assignments = append(t.Assignments, brokerIDs)
+		}
+		r.Topics[i] = t
+	}
+	timeout, err := d.Int32()
+	if err != nil {
+		return err
+	}
+	r.Timeout = time.Duration(timeout) * time.Millisecond
+	r.ValidateOnly, err = d.Bool()
+	return err
+}
+
+func (r *CreatePartitionsRequest) Key() int16 {
+	return CreatePartitionsKey
+}
+
+func (r *CreatePartitionsRequest) Version() int16 {
+	return r.APIVersion
+}
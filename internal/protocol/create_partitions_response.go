@@ -0,0 +1,65 @@
+package protocol
+
+import "time"
+
+type CreatePartitionsTopicResult struct {
+	Name         string
+	ErrorCode    int16
+	ErrorMessage *string
+}
+
+type CreatePartitionsResponse struct {
+	APIVersion int16
+
+	ThrottleTime time.Duration
+	Results      []*CreatePartitionsTopicResult
+}
+
+func (r *CreatePartitionsResponse) Encode(e PacketEncoder) (err error) {
+	e.PutInt32(int32(r.ThrottleTime / time.Millisecond))
+	if err = e.PutArrayLength(len(r.Results)); err != nil {
+		return err
+	}
+	for _, t := range r.Results {
+		if err = e.PutString(t.Name); err != nil {
+			return err
+		}
+		e.PutInt16(t.ErrorCode)
+		if err = e.PutNullableString(t.ErrorMessage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *CreatePartitionsResponse) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	throttle, err := d.Int32()
+	if err != nil {
+		return err
+	}
+	r.ThrottleTime = time.Duration(throttle) * time.Millisecond
+	n, err := d.ArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Results = make([]*CreatePartitionsTopicResult, n)
+	for i := range r.Results {
+		t := &CreatePartitionsTopicResult{}
+		if t.Name, err = d.String(); err != nil {
+			return err
+		}
+		if t.ErrorCode, err = d.Int16(); err != nil {
+			return err
+		}
+		if t.ErrorMessage, err = d.NullableString(); err != nil {
+			return err
+		}
+		r.Results[i] = t
+	}
+	return nil
+}
+
+func (r *CreatePartitionsResponse) Version() int16 {
+	return r.APIVersion
+}
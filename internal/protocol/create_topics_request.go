@@ -0,0 +1,138 @@
+package protocol
+
+import "time"
+
+type ReplicaAssignment struct {
+	Partition int32
+	BrokerIDs []int32
+}
+
+type CreatableTopicConfig struct {
+	Name  string
+	Value *string
+}
+
+type CreatableTopic struct {
+	Name string
+	// NumPartitions and ReplicationFactor are -1 when Assignments are given,
+	// from version 4 on they may also be -1 to use the broker defaults
+	NumPartitions     int32
+	ReplicationFactor int16
+	Assignments       []*ReplicaAssignment
+	Configs           []*CreatableTopicConfig
+}
+
+type CreateTopicsRequest struct {
+	APIVersion int16
+
+	Topics       []*CreatableTopic
+	Timeout      time.Duration
+	ValidateOnly bool
+}
+
+func (r *CreateTopicsRequest) Encode(e PacketEncoder) (err error) {
+	if err = e.PutArrayLength(len(r.Topics)); err != nil {
+		return err
+	}
+	for _, t := range r.Topics {
+		if err = e.PutString(t.Name); err != nil {
+			return err
+		}
+		e.PutInt32(t.NumPartitions)
+		e.PutInt16(t.ReplicationFactor)
+		if err = e.PutArrayLength(len(t.Assignments)); err != nil {
+			return err
+		}
+		for _, a := range t.Assignments {
+			e.PutInt32(a.Partition)
+			if err = e.PutInt32Array(a.BrokerIDs); err != nil {
+				return err
+			}
+		}
+		if err = e.PutArrayLength(len(t.Configs)); err != nil {
+			return err
+		}
+		for _, c := range t.Configs {
+			if err = e.PutString(c.Name); err != nil {
+				return err
+			}
+			if err = e.PutNullableString(c.Value); err != nil {
+				return err
+			}
+		}
+	}
+	e.PutInt32(int32(r.Timeout / time.Millisecond))
+	if r.APIVersion >= 1 {
+		e.PutBool(r.ValidateOnly)
+	}
+	return nil
+}
+
+func (r *CreateTopicsRequest) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	n, err := d.ArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Topics = make([]*CreatableTopic, n)
+	for i := range r.Topics {
+		t := &CreatableTopic{}
+		if t.Name, err = d.String(); err != nil {
+			return err
+		}
+		if t.NumPartitions, err = d.Int32(); err != nil {
+			return err
+		}
+		if t.ReplicationFactor, err = d.Int16(); err != nil {
+			return err
+		}
+		an, err := d.ArrayLength()
+		if err != nil {
+			return err
+		}
+		t.Assignments = make([]*ReplicaAssignment, an)
+		for j := range t.Assignments {
+			a := &ReplicaAssignment{}
+			if a.Partition, err = d.Int32(); err != nil {
+				return err
+			}
+			if a.BrokerIDs, err = d.Int32Array(); err != nil {
+				return err
+			}
+			t.Assignments[j] = a
+		}
+		cn, err := d.ArrayLength()
+		if err != nil {
+			return err
+		}
+		t.Configs = make([]*CreatableTopicConfig, cn)
+		for j := range t.Configs {
+			c := &CreatableTopicConfig{}
+			if c.Name, err = d.String(); err != nil {
+				return err
+			}
+			if c.Value, err = d.NullableString(); err != nil {
+				return err
+			}
+			t.Configs[j] = c
+		}
+		r.Topics[i] = t
+	}
+	timeout, err := d.Int32()
+	if err != nil {
+		return err
+	}
+	r.Timeout = time.Duration(timeout) * time.Millisecond
+	if version >= 1 {
+		r.ValidateOnly, err = d.Bool()
+	}
+	return err
+}
+
+func (r *CreateTopicsRequest) Key() int16 {
+	return CreateTopicsKey
+}
+
+func (r *CreateTopicsRequest) Version() int16 {
+	return r.APIVersion
+}
@@ -0,0 +1,73 @@
+package protocol
+
+import "time"
+
+type CreatableTopicResult struct {
+	Name         string
+	ErrorCode    int16
+	ErrorMessage *string
+}
+
+type CreateTopicsResponse struct {
+	APIVersion int16
+
+	ThrottleTime time.Duration
+	Topics       []*CreatableTopicResult
+}
+
+func (r *CreateTopicsResponse) Encode(e PacketEncoder) (err error) {
+	if r.APIVersion >= 2 {
+		e.PutInt32(int32(r.ThrottleTime / time.Millisecond))
+	}
+	if err = e.PutArrayLength(len(r.Topics)); err != nil {
+		return err
+	}
+	for _, t := range r.Topics {
+		if err = e.PutString(t.Name); err != nil {
+			return err
+		}
+		e.PutInt16(t.ErrorCode)
+		if r.APIVersion >= 1 {
+			if err = e.PutNullableString(t.ErrorMessage); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *CreateTopicsResponse) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	if version >= 2 {
+		throttle, err := d.Int32()
+		if err != nil {
+			return err
+		}
+		r.ThrottleTime = time.Duration(throttle) * time.Millisecond
+	}
+	n, err := d.ArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Topics = make([]*CreatableTopicResult, n)
+	for i := range r.Topics {
+		t := &CreatableTopicResult{}
+		if t.Name, err = d.String(); err != nil {
+			return err
+		}
+		if t.ErrorCode, err = d.Int16(); err != nil {
+			return err
+		}
+		if version >= 1 {
+			if t.ErrorMessage, err = d.NullableString(); err != nil {
+				return err
+			}
+		}
+		r.Topics[i] = t
+	}
+	return nil
+}
+
+func (r *CreateTopicsResponse) Version() int16 {
+	return r.APIVersion
+}
@@ -31,6 +31,15 @@ type PacketDecoder interface {
 	PeekInt8(offset int) (int8, error) // similar to peek, but just one byte
 	Varint() (int64, error)
 	VarintBytes() ([]byte, error)
+	// Compact encodings and tagged fields of the flexible versions
+	UVarint() (uint64, error)
+	CompactArrayLength() (int, error)
+	CompactBytes() ([]byte, error)
+	CompactString() (string, error)
+	CompactNullableString() (*string, error)
+	CompactStringArray() ([]string, error)
+	CompactInt32Array() ([]int32, error)
+	TaggedFields() (map[uint64][]byte, error)
 	// Added ex
 	Push(pd PushDecoder) error
 	Pop() error
@@ -52,6 +61,13 @@ type PushDecoder interface {
 	Check(curOffset int, buf []byte) error
 }
 
+// DynamicPushDecoder extends the interface of PushDecoder for uses cases where the length of the
+// fields itself is dynamic (ie varint)
+type DynamicPushDecoder interface {
+	PushDecoder
+	Decoder
+}
+
 func Decode(b []byte, in VersionedDecoder, version int16) error {
 	d := NewDecoder(b)
 	return in.Decode(d, version)
@@ -82,12 +98,20 @@ func (d *ByteDecoder) Bool() (bool, error) {
 }
 
 func (d *ByteDecoder) Int8() (int8, error) {
+	if d.remaining() < 1 {
+		d.off = len(d.b)
+		return -1, ErrInsufficientData
+	}
 	tmp := int8(d.b[d.off])
 	d.off++
 	return tmp, nil
 }
 
 func (d *ByteDecoder) Int16() (int16, error) {
+	if d.remaining() < 2 {
+		d.off = len(d.b)
+		return -1, ErrInsufficientData
+	}
 	tmp := int16(Encoding.Uint16(d.b[d.off:]))
 	d.off += 2
 	return tmp, nil
@@ -272,7 +296,7 @@ func (d *ByteDecoder) StringArray() ([]string, error) {
 		d.off = len(d.b)
 		return nil, ErrInsufficientData
 	}
-	n := int(Encoding.Uint32(d.b[d.off:]))
+	n := int(int32(Encoding.Uint32(d.b[d.off:])))
 	d.off += 4
 
 	if n == 0 {
@@ -283,6 +307,11 @@ func (d *ByteDecoder) StringArray() ([]string, error) {
 		return nil, ErrInvalidArrayLength
 	}
 
+	if n > d.remaining() {
+		d.off = len(d.b)
+		return nil, ErrInsufficientData
+	}
+
 	ret := make([]string, n)
 	for i := range ret {
 		if str, err := d.String(); err != nil {
@@ -350,13 +379,154 @@ func (d *ByteDecoder) VarintBytes() ([]byte, error) {
 	return d.RawBytes(int(tmp))
 }
 
+// Flexible versions
+
+func (d *ByteDecoder) UVarint() (uint64, error) {
+	tmp, n := binary.Uvarint(d.b[d.off:])
+	if n == 0 {
+		d.off = len(d.b)
+		return 0, ErrInsufficientData
+	}
+	if n < 0 {
+		d.off -= n
+		return 0, ErrVarintOverflow
+	}
+	d.off += n
+	return tmp, nil
+}
+
+// compactLength reads the length of a compact string, bytes or array, -1 for null
+func (d *ByteDecoder) compactLength() (int, error) {
+	tmp, err := d.UVarint()
+	if err != nil {
+		return -1, err
+	}
+	n := int(tmp) - 1
+	if tmp > math.MaxInt32 || n > d.remaining() {
+		d.off = len(d.b)
+		return -1, ErrInsufficientData
+	}
+	return n, nil
+}
+
+// CompactArrayLength reads the length of a compact array, -1 for a null array
+func (d *ByteDecoder) CompactArrayLength() (int, error) {
+	n, err := d.compactLength()
+	if err != nil {
+		return -1, err
+	}
+	if n > 2*math.MaxUint16 {
+		return -1, ErrInvalidArrayLength
+	}
+	return n, nil
+}
+
+func (d *ByteDecoder) CompactBytes() ([]byte, error) {
+	n, err := d.compactLength()
+	if err != nil || n == -1 {
+		return nil, err
+	}
+	return d.RawBytes(n)
+}
+
+func (d *ByteDecoder) CompactString() (string, error) {
+	n, err := d.compactLength()
+	if err != nil || n == -1 {
+		return "", err
+	}
+	tmpStr := string(d.b[d.off : d.off+n])
+	d.off += n
+	return tmpStr, nil
+}
+
+func (d *ByteDecoder) CompactNullableString() (*string, error) {
+	n, err := d.compactLength()
+	if err != nil || n == -1 {
+		return nil, err
+	}
+	tmpStr := string(d.b[d.off : d.off+n])
+	d.off += n
+	return &tmpStr, nil
+}
+
+func (d *ByteDecoder) CompactStringArray() ([]string, error) {
+	n, err := d.CompactArrayLength()
+	if err != nil || n <= 0 {
+		return nil, err
+	}
+	ret := make([]string, n)
+	for i := range ret {
+		if ret[i], err = d.CompactString(); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func (d *ByteDecoder) CompactInt32Array() ([]int32, error) {
+	n, err := d.CompactArrayLength()
+	if err != nil || n <= 0 {
+		return nil, err
+	}
+	if d.remaining() < 4*n {
+		d.off = len(d.b)
+		return nil, ErrInsufficientData
+	}
+	ret := make([]int32, n)
+	for i := range ret {
+		ret[i] = int32(Encoding.Uint32(d.b[d.off:]))
+		d.off += 4
+	}
+	return ret, nil
+}
+
+// TaggedFields reads a tagged fields section and returns the raw fields by tag, or
+// nil if it is empty
+func (d *ByteDecoder) TaggedFields() (map[uint64][]byte, error) {
+	n, err := d.UVarint()
+	if err != nil || n == 0 {
+		return nil, err
+	}
+	if n > uint64(d.remaining()) {
+		d.off = len(d.b)
+		return nil, ErrInsufficientData
+	}
+	fields := make(map[uint64][]byte, n)
+	for i := uint64(0); i < n; i++ {
+		tag, err := d.UVarint()
+		if err != nil {
+			return nil, err
+		}
+		size, err := d.UVarint()
+		if err != nil {
+			return nil, err
+		}
+		if size > uint64(d.remaining()) {
+			d.off = len(d.b)
+			return nil, ErrInsufficientData
+		}
+		if fields[tag], err = d.RawBytes(int(size)); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
 // Added Ex
 func (d *ByteDecoder) Push(pd PushDecoder) error {
 	pd.SaveOffset(d.off)
-	reserved := pd.ReserveSize()
-	if d.remaining() < reserved {
-		d.off = len(d.b)
-		return ErrInsufficientData
+
+	var reserved int
+	if dpd, ok := pd.(DynamicPushDecoder); ok {
+		if err := dpd.Decode(d); err != nil {
+			return err
+		}
+	} else {
+		reserved = pd.ReserveSize()
+		if d.remaining() < reserved {
+			d.off = len(d.b)
+			return ErrInsufficientData
+		}
 	}
 	d.stack = append(d.stack, pd)
 	d.off += reserved
@@ -0,0 +1,86 @@
+package protocol
+
+import "time"
+
+type DeleteRecordsPartition struct {
+	Partition int32
+	// Offset is the offset records are deleted before, -1 for the high watermark
+	Offset int64
+}
+
+type DeleteRecordsTopic struct {
+	Name       string
+	Partitions []*DeleteRecordsPartition
+}
+
+type DeleteRecordsRequest struct {
+	APIVersion int16
+
+	Topics  []*DeleteRecordsTopic
+	Timeout time.Duration
+}
+
+func (r *DeleteRecordsRequest) Encode(e PacketEncoder) (err error) {
+	if err = e.PutArrayLength(len(r.Topics)); err != nil {
+		return err
+	}
+	for _, t := range r.Topics {
+		if err = e.PutString(t.Name); err != nil {
+			return err
+		}
+		if err = e.PutArrayLength(len(t.Partitions)); err != nil {
+			return err
+		}
+		for _, p := range t.Partitions {
+			e.PutInt32(p.Partition)
+			e.PutInt64(p.Offset)
+		}
+	}
+	e.PutInt32(int32(r.Timeout / time.Millisecond))
+	return nil
+}
+
+func (r *DeleteRecordsRequest) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	n, err := d.ArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Topics = make([]*DeleteRecordsTopic, n)
+	for i := range r.Topics {
+		t := &DeleteRecordsTopic{}
+		if t.Name, err = d.String(); err != nil {
+			return err
+		}
+		pn, err := d.ArrayLength()
+		if err != nil {
+			return err
+		}
+		t.Partitions = make([]*DeleteRecordsPartition, pn)
+		for j := range t.Partitions {
+			p := &DeleteRecordsPartition{}
+			if p.Partition, err = d.Int32(); err != nil {
+				return err
+			}
+			if p.Offset, err = d.Int64(); err != nil {
+				return err
+			}
+			t.Partitions[j] = p
+		}
+		r.Topics[i] = t
+	}
+	timeout, err := d.Int32()
+	if err != nil {
+		return err
+	}
+	r.Timeout = time.Duration(timeout) * time.Millisecond
+	return nil
+}
+
+func (r *DeleteRecordsRequest) Key() int16 {
+	return DeleteRecordsKey
+}
+
+func (r *DeleteRecordsRequest) Version() int16 {
+	return r.APIVersion
+}
@@ -0,0 +1,62 @@
+package protocol
+
+import "time"
+
+type DeletableTopicResult struct {
+	Name      string
+	ErrorCode int16
+}
+
+type DeleteTopicsResponse struct {
+	APIVersion int16
+
+	ThrottleTime time.Duration
+	Responses    []*DeletableTopicResult
+}
+
+func (r *DeleteTopicsResponse) Encode(e PacketEncoder) (err error) {
+	if r.APIVersion >= 1 {
+		e.PutInt32(int32(r.ThrottleTime / time.Millisecond))
+	}
+	if err = e.PutArrayLength(len(r.Responses)); err != nil {
+		return err
+	}
+	for _, t := range r.Responses {
+		if err = e.PutString(t.Name); err != nil {
+			return err
+		}
+		e.PutInt16(t.ErrorCode)
+	}
+	return nil
+}
+
+func (r *DeleteTopicsResponse) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	if version >= 1 {
+		throttle, err := d.Int32()
+		if err != nil {
+			return err
+		}
+		r.ThrottleTime = time.Duration(throttle) * time.Millisecond
+	}
+	n, err := d.ArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Responses = make([]*DeletableTopicResult, n)
+	for i := range r.Responses {
+		t := &DeletableTopicResult{}
+		if t.Name, err = d.String(); err != nil {
+			return err
+		}
+		if t.ErrorCode, err = d.Int16(); err != nil {
+			return err
+		}
+		r.Responses[i] = t
+	}
+	return nil
+}
+
+func (r *DeleteTopicsResponse) Version() int16 {
+	return r.APIVersion
+}
@@ -3,6 +3,7 @@ package protocol
 import (
 	"encoding/binary"
 	"math"
+	"sort"
 )
 
 type PacketEncoder interface {
@@ -22,6 +23,15 @@ type PacketEncoder interface {
 	// Added
 	PutVarint(in int64)
 	PutVarintBytes(in []byte) error
+	// Compact encodings and tagged fields of the flexible versions
+	PutUVarint(in uint64)
+	PutCompactArrayLength(in int) error
+	PutCompactBytes(in []byte) error
+	PutCompactString(in string) error
+	PutCompactNullableString(in *string) error
+	PutCompactStringArray(in []string) error
+	PutCompactInt32Array(in []int32) error
+	PutTaggedFields(in map[uint64][]byte) error
 	// Added
 	Push(pe PushEncoder)
 	Pop()
@@ -33,6 +43,13 @@ type PushEncoder interface {
 	Fill(curOffset int, buf []byte) error
 }
 
+// dynamicPushEncoder extends the interface of PushEncoder for uses cases where the length of the
+// fields itself is dynamic (ie varint)
+type dynamicPushEncoder interface {
+	PushEncoder
+	adjustLength(currOffset int) int
+}
+
 type Encoder interface {
 	Encode(e PacketEncoder) error
 }
@@ -56,7 +73,7 @@ func Encode(e Encoder) ([]byte, error) {
 
 type LenEncoder struct {
 	Length int
-	stack  []int
+	stack  []PushEncoder
 }
 
 func (e *LenEncoder) PutBool(in bool) {
@@ -170,13 +187,95 @@ func (e *LenEncoder) PutVarintBytes(in []byte) error {
 	return e.PutRawBytes(in)
 }
 
+// Flexible versions
+
+func (e *LenEncoder) PutUVarint(in uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	e.Length += binary.PutUvarint(buf[:], in)
+}
+
+// PutCompactArrayLength writes the length of a compact array, -1 for a null array
+func (e *LenEncoder) PutCompactArrayLength(in int) error {
+	if in > math.MaxInt32 {
+		return ErrInvalidArrayLength
+	}
+	e.PutUVarint(uint64(in + 1))
+	return nil
+}
+
+func (e *LenEncoder) PutCompactBytes(in []byte) error {
+	if in == nil {
+		e.PutUVarint(0)
+		return nil
+	}
+	e.PutUVarint(uint64(len(in) + 1))
+	return e.PutRawBytes(in)
+}
+
+func (e *LenEncoder) PutCompactString(in string) error {
+	if len(in) > math.MaxInt16 {
+		return ErrInvalidStringLength
+	}
+	e.PutUVarint(uint64(len(in) + 1))
+	e.Length += len(in)
+	return nil
+}
+
+func (e *LenEncoder) PutCompactNullableString(in *string) error {
+	if in == nil {
+		e.PutUVarint(0)
+		return nil
+	}
+	return e.PutCompactString(*in)
+}
+
+func (e *LenEncoder) PutCompactStringArray(in []string) error {
+	if err := e.PutCompactArrayLength(len(in)); err != nil {
+		return err
+	}
+	for _, str := range in {
+		if err := e.PutCompactString(str); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *LenEncoder) PutCompactInt32Array(in []int32) error {
+	if err := e.PutCompactArrayLength(len(in)); err != nil {
+		return err
+	}
+	e.Length += 4 * len(in)
+	return nil
+}
+
+func (e *LenEncoder) PutTaggedFields(in map[uint64][]byte) error {
+	e.PutUVarint(uint64(len(in)))
+	for tag, field := range in {
+		e.PutUVarint(tag)
+		e.PutUVarint(uint64(len(field)))
+		if err := e.PutRawBytes(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Added
 
 func (e *LenEncoder) Push(pe PushEncoder) {
+	pe.SaveOffset(e.Length)
 	e.Length += pe.ReserveSize()
+	e.stack = append(e.stack, pe)
 }
 
-func (e *LenEncoder) Pop() {}
+func (e *LenEncoder) Pop() {
+	pe := e.stack[len(e.stack)-1]
+	e.stack = e.stack[:len(e.stack)-1]
+	if de, ok := pe.(dynamicPushEncoder); ok {
+		e.Length += de.adjustLength(e.Length)
+	}
+}
 
 type ByteEncoder struct {
 	b     []byte
@@ -306,6 +405,82 @@ func (e *ByteEncoder) PutVarintBytes(in []byte) error {
 	return e.PutRawBytes(in)
 }
 
+// Flexible versions
+
+func (e *ByteEncoder) PutUVarint(in uint64) {
+	e.off += binary.PutUvarint(e.b[e.off:], in)
+}
+
+// PutCompactArrayLength writes the length of a compact array, -1 for a null array
+func (e *ByteEncoder) PutCompactArrayLength(in int) error {
+	e.PutUVarint(uint64(in + 1))
+	return nil
+}
+
+func (e *ByteEncoder) PutCompactBytes(in []byte) error {
+	if in == nil {
+		e.PutUVarint(0)
+		return nil
+	}
+	e.PutUVarint(uint64(len(in) + 1))
+	return e.PutRawBytes(in)
+}
+
+func (e *ByteEncoder) PutCompactString(in string) error {
+	e.PutUVarint(uint64(len(in) + 1))
+	copy(e.b[e.off:], in)
+	e.off += len(in)
+	return nil
+}
+
+func (e *ByteEncoder) PutCompactNullableString(in *string) error {
+	if in == nil {
+		e.PutUVarint(0)
+		return nil
+	}
+	return e.PutCompactString(*in)
+}
+
+func (e *ByteEncoder) PutCompactStringArray(in []string) error {
+	if err := e.PutCompactArrayLength(len(in)); err != nil {
+		return err
+	}
+	for _, val := range in {
+		if err := e.PutCompactString(val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *ByteEncoder) PutCompactInt32Array(in []int32) error {
+	if err := e.PutCompactArrayLength(len(in)); err != nil {
+		return err
+	}
+	for _, val := range in {
+		e.PutInt32(val)
+	}
+	return nil
+}
+
+// PutTaggedFields writes a tagged fields section, the tags in ascending order
+func (e *ByteEncoder) PutTaggedFields(in map[uint64][]byte) error {
+	e.PutUVarint(uint64(len(in)))
+	tags := make([]uint64, 0, len(in))
+	for tag := range in {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+	for _, tag := range tags {
+		e.PutUVarint(tag)
+		e.PutUVarint(uint64(len(in[tag])))
+		if err := e.PutRawBytes(in[tag]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Added
 
 func (e *ByteEncoder) Push(pe PushEncoder) {
@@ -0,0 +1,271 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+// encodeFunc encodes fields with the length encoder then the byte encoder, like Encode
+func encodeFunc(t *testing.T, put func(e PacketEncoder) error) []byte {
+	t.Helper()
+	var lenEnc LenEncoder
+	if err := put(&lenEnc); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, lenEnc.Length)
+	byteEnc := NewByteEncoder(b)
+	if err := put(byteEnc); err != nil {
+		t.Fatal(err)
+	}
+	if byteEnc.off != lenEnc.Length {
+		t.Fatalf("wrote %d bytes, measured %d", byteEnc.off, lenEnc.Length)
+	}
+	return b
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestCompactEncoding(t *testing.T) {
+	tests := []struct {
+		name string
+		put  func(e PacketEncoder) error
+		get  func(d *ByteDecoder) (interface{}, error)
+		want interface{}
+		hex  string
+	}{
+		{
+			name: "uvarint 0",
+			put:  func(e PacketEncoder) error { e.PutUVarint(0); return nil },
+			get:  func(d *ByteDecoder) (interface{}, error) { return d.UVarint() },
+			want: uint64(0),
+			hex:  "00",
+		},
+		{
+			name: "uvarint 127",
+			put:  func(e PacketEncoder) error { e.PutUVarint(127); return nil },
+			get:  func(d *ByteDecoder) (interface{}, error) { return d.UVarint() },
+			want: uint64(127),
+			hex:  "7f",
+		},
+		{
+			name: "uvarint 128",
+			put:  func(e PacketEncoder) error { e.PutUVarint(128); return nil },
+			get:  func(d *ByteDecoder) (interface{}, error) { return d.UVarint() },
+			want: uint64(128),
+			hex:  "8001",
+		},
+		{
+			name: "uvarint 300",
+			put:  func(e PacketEncoder) error { e.PutUVarint(300); return nil },
+			get:  func(d *ByteDecoder) (interface{}, error) { return d.UVarint() },
+			want: uint64(300),
+			hex:  "ac02",
+		},
+		{
+			name: "compact string",
+			put:  func(e PacketEncoder) error { return e.PutCompactString("abc") },
+			get:  func(d *ByteDecoder) (interface{}, error) { return d.CompactString() },
+			want: "abc",
+			hex:  "04616263",
+		},
+		{
+			name: "empty compact string",
+			put:  func(e PacketEncoder) error { return e.PutCompactString("") },
+			get:  func(d *ByteDecoder) (interface{}, error) { return d.CompactString() },
+			want: "",
+			hex:  "01",
+		},
+		{
+			name: "compact nullable string",
+			put:  func(e PacketEncoder) error { return e.PutCompactNullableString(stringPtr("ab")) },
+			get:  func(d *ByteDecoder) (interface{}, error) { return d.CompactNullableString() },
+			want: stringPtr("ab"),
+			hex:  "036162",
+		},
+		{
+			name: "null compact string",
+			put:  func(e PacketEncoder) error { return e.PutCompactNullableString(nil) },
+			get:  func(d *ByteDecoder) (interface{}, error) { return d.CompactNullableString() },
+			want: (*string)(nil),
+			hex:  "00",
+		},
+		{
+			name: "compact bytes",
+			put:  func(e PacketEncoder) error { return e.PutCompactBytes([]byte{1, 2}) },
+			get:  func(d *ByteDecoder) (interface{}, error) { return d.CompactBytes() },
+			want: []byte{1, 2},
+			hex:  "030102",
+		},
+		{
+			name: "empty compact bytes",
+			put:  func(e PacketEncoder) error { return e.PutCompactBytes([]byte{}) },
+			get:  func(d *ByteDecoder) (interface{}, error) { return d.CompactBytes() },
+			want: []byte{},
+			hex:  "01",
+		},
+		{
+			name: "null compact bytes",
+			put:  func(e PacketEncoder) error { return e.PutCompactBytes(nil) },
+			get:  func(d *ByteDecoder) (interface{}, error) { return d.CompactBytes() },
+			want: []byte(nil),
+			hex:  "00",
+		},
+		{
+			name: "empty compact array",
+			put:  func(e PacketEncoder) error { return e.PutCompactArrayLength(0) },
+			get:  func(d *ByteDecoder) (interface{}, error) { return d.CompactArrayLength() },
+			want: 0,
+			hex:  "01",
+		},
+		{
+			name: "null compact array",
+			put:  func(e PacketEncoder) error { return e.PutCompactArrayLength(-1) },
+			get:  func(d *ByteDecoder) (interface{}, error) { return d.CompactArrayLength() },
+			want: -1,
+			hex:  "00",
+		},
+		{
+			name: "compact int32 array",
+			put:  func(e PacketEncoder) error { return e.PutCompactInt32Array([]int32{1, -1}) },
+			get:  func(d *ByteDecoder) (interface{}, error) { return d.CompactInt32Array() },
+			want: []int32{1, -1},
+			hex:  "0300000001ffffffff",
+		},
+		{
+			name: "compact string array",
+			put:  func(e PacketEncoder) error { return e.PutCompactStringArray([]string{"a", "bc"}) },
+			get:  func(d *ByteDecoder) (interface{}, error) { return d.CompactStringArray() },
+			want: []string{"a", "bc"},
+			hex:  "030261036263",
+		},
+		{
+			name: "empty tagged fields",
+			put:  func(e PacketEncoder) error { return e.PutTaggedFields(nil) },
+			get:  func(d *ByteDecoder) (interface{}, error) { return d.TaggedFields() },
+			want: map[uint64][]byte(nil),
+			hex:  "00",
+		},
+		{
+			name: "tagged fields",
+			put: func(e PacketEncoder) error {
+				return e.PutTaggedFields(map[uint64][]byte{1: {0xaa}, 0: {}})
+			},
+			get:  func(d *ByteDecoder) (interface{}, error) { return d.TaggedFields() },
+			want: map[uint64][]byte{0: {}, 1: {0xaa}},
+			hex:  "0200000101aa",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := encodeFunc(t, tt.put)
+			if want := mustHex(t, tt.hex); !bytes.Equal(b, want) {
+				t.Fatalf("encoded %x, want %x", b, want)
+			}
+			d := NewDecoder(b)
+			got, err := tt.get(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("decoded %#v, want %#v", got, tt.want)
+			}
+			if d.remaining() != 0 {
+				t.Fatalf("%d bytes left", d.remaining())
+			}
+		})
+	}
+}
+
+func TestCompactDecodingErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+		get  func(d *ByteDecoder) error
+		want error
+	}{
+		{
+			name: "string longer than the packet",
+			hex:  "0561",
+			get:  func(d *ByteDecoder) error { _, err := d.CompactString(); return err },
+			want: ErrInsufficientData,
+		},
+		{
+			name: "bytes longer than the packet",
+			hex:  "0401",
+			get:  func(d *ByteDecoder) error { _, err := d.CompactBytes(); return err },
+			want: ErrInsufficientData,
+		},
+		{
+			name: "int32 array longer than the packet",
+			hex:  "0300000001",
+			get:  func(d *ByteDecoder) error { _, err := d.CompactInt32Array(); return err },
+			want: ErrInsufficientData,
+		},
+		{
+			name: "array longer than the packet",
+			hex:  "04",
+			get:  func(d *ByteDecoder) error { _, err := d.CompactArrayLength(); return err },
+			want: ErrInsufficientData,
+		},
+		{
+			name: "truncated uvarint",
+			hex:  "80",
+			get:  func(d *ByteDecoder) error { _, err := d.UVarint(); return err },
+			want: ErrInsufficientData,
+		},
+		{
+			name: "tagged field longer than the packet",
+			hex:  "010005aa",
+			get:  func(d *ByteDecoder) error { _, err := d.TaggedFields(); return err },
+			want: ErrInsufficientData,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.get(NewDecoder(mustHex(t, tt.hex))); err != tt.want {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlexibleHelpers(t *testing.T) {
+	tests := []struct {
+		name     string
+		flexible bool
+		put      func(e PacketEncoder, flexible bool) error
+		hex      string
+	}{
+		{"classic string", false, func(e PacketEncoder, f bool) error { return putString(e, "ab", f) }, "00026162"},
+		{"compact string", true, func(e PacketEncoder, f bool) error { return putString(e, "ab", f) }, "036162"},
+		{"classic null string", false, func(e PacketEncoder, f bool) error { return putNullableString(e, nil, f) }, "ffff"},
+		{"compact null string", true, func(e PacketEncoder, f bool) error { return putNullableString(e, nil, f) }, "00"},
+		{"classic null array", false, func(e PacketEncoder, f bool) error { return putArrayLength(e, -1, f) }, "ffffffff"},
+		{"compact null array", true, func(e PacketEncoder, f bool) error { return putArrayLength(e, -1, f) }, "00"},
+		{"classic int32 array", false, func(e PacketEncoder, f bool) error { return putInt32Array(e, []int32{7}, f) }, "0000000100000007"},
+		{"compact int32 array", true, func(e PacketEncoder, f bool) error { return putInt32Array(e, []int32{7}, f) }, "0200000007"},
+		{"classic tagged fields", false, func(e PacketEncoder, f bool) error { return putTaggedFields(e, f) }, ""},
+		{"compact tagged fields", true, func(e PacketEncoder, f bool) error { return putTaggedFields(e, f) }, "00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := encodeFunc(t, func(e PacketEncoder) error { return tt.put(e, tt.flexible) })
+			if want := mustHex(t, tt.hex); !bytes.Equal(b, want) {
+				t.Fatalf("encoded %x, want %x", b, want)
+			}
+		})
+	}
+}
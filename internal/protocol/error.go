@@ -1,7 +1,10 @@
 package protocol
 
 // See https://kafka.apache.org/protocol#protocol_error_codes - for details.
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 var (
 	ErrUnknown                            = Error{code: -1, msg: "unknown"}
@@ -61,6 +64,33 @@ var (
 	ErrTransactionalIdAuthorizationFailed = Error{code: 53, msg: "transactional id authorization failed"}
 	ErrSecurityDisabled                   = Error{code: 54, msg: "security disabled"}
 	ErrOperationNotAttempted              = Error{code: 55, msg: "operation not attempted"}
+	ErrKafkaStorageError                  = Error{code: 56, msg: "kafka storage error"}
+	ErrLogDirNotFound                     = Error{code: 57, msg: "log dir not found"}
+	ErrSaslAuthenticationFailed           = Error{code: 58, msg: "sasl authentication failed"}
+	ErrUnknownProducerId                  = Error{code: 59, msg: "unknown producer id"}
+	ErrReassignmentInProgress             = Error{code: 60, msg: "reassignment in progress"}
+	ErrDelegationTokenAuthDisabled        = Error{code: 61, msg: "delegation token auth disabled"}
+	ErrDelegationTokenNotFound            = Error{code: 62, msg: "delegation token not found"}
+	ErrDelegationTokenOwnerMismatch       = Error{code: 63, msg: "delegation token owner mismatch"}
+	ErrDelegationTokenRequestNotAllowed   = Error{code: 64, msg: "delegation token request not allowed"}
+	ErrDelegationTokenAuthorizationFailed = Error{code: 65, msg: "delegation token authorization failed"}
+	ErrDelegationTokenExpired             = Error{code: 66, msg: "delegation token expired"}
+	ErrInvalidPrincipalType               = Error{code: 67, msg: "invalid principal type"}
+	ErrNonEmptyGroup                      = Error{code: 68, msg: "non empty group"}
+	ErrGroupIdNotFound                    = Error{code: 69, msg: "group id not found"}
+	ErrFetchSessionIdNotFound             = Error{code: 70, msg: "fetch session id not found"}
+	ErrInvalidFetchSessionEpoch           = Error{code: 71, msg: "invalid fetch session epoch"}
+	ErrListenerNotFound                   = Error{code: 72, msg: "listener not found"}
+	ErrTopicDeletionDisabled              = Error{code: 73, msg: "topic deletion disabled"}
+	ErrFencedLeaderEpoch                  = Error{code: 74, msg: "fenced leader epoch"}
+	ErrUnknownLeaderEpoch                 = Error{code: 75, msg: "unknown leader epoch"}
+	ErrUnsupportedCompressionType         = Error{code: 76, msg: "unsupported compression type"}
+	ErrStaleBrokerEpoch                   = Error{code: 77, msg: "stale broker epoch"}
+	ErrOffsetNotAvailable                 = Error{code: 78, msg: "offset not available"}
+	ErrMemberIdRequired                   = Error{code: 79, msg: "member id required"}
+	ErrPreferredLeaderNotAvailable        = Error{code: 80, msg: "preferred leader not available"}
+	ErrGroupMaxSizeReached                = Error{code: 81, msg: "group max size reached"}
+	ErrFencedInstanceId                   = Error{code: 82, msg: "fenced instance id"}
 
 	// Errs maps err codes to their errs.
 	Errs = map[int16]Error{
@@ -121,9 +151,48 @@ var (
 		53: ErrTransactionalIdAuthorizationFailed,
 		54: ErrSecurityDisabled,
 		55: ErrOperationNotAttempted,
+		56: ErrKafkaStorageError,
+		57: ErrLogDirNotFound,
+		58: ErrSaslAuthenticationFailed,
+		59: ErrUnknownProducerId,
+		60: ErrReassignmentInProgress,
+		61: ErrDelegationTokenAuthDisabled,
+		62: ErrDelegationTokenNotFound,
+		63: ErrDelegationTokenOwnerMismatch,
+		64: ErrDelegationTokenRequestNotAllowed,
+		65: ErrDelegationTokenAuthorizationFailed,
+		66: ErrDelegationTokenExpired,
+		67: ErrInvalidPrincipalType,
+		68: ErrNonEmptyGroup,
+		69: ErrGroupIdNotFound,
+		70: ErrFetchSessionIdNotFound,
+		71: ErrInvalidFetchSessionEpoch,
+		72: ErrListenerNotFound,
+		73: ErrTopicDeletionDisabled,
+		74: ErrFencedLeaderEpoch,
+		75: ErrUnknownLeaderEpoch,
+		76: ErrUnsupportedCompressionType,
+		77: ErrStaleBrokerEpoch,
+		78: ErrOffsetNotAvailable,
+		79: ErrMemberIdRequired,
+		80: ErrPreferredLeaderNotAvailable,
+		81: ErrGroupMaxSizeReached,
+		82: ErrFencedInstanceId,
 	}
 )
 
+// ErrorByName returns an error from its name, the description in upper snake case
+// like NOT_LEADER_FOR_PARTITION
+func ErrorByName(name string) (Error, bool) {
+	msg := strings.ToLower(strings.Replace(name, "_", " ", -1))
+	for _, err := range Errs {
+		if err.msg == msg {
+			return err, true
+		}
+	}
+	return Error{}, false
+}
+
 // Error represents a protocol err. It makes it so the errors can have their
 // error code and description too.
 type Error struct {
@@ -0,0 +1,223 @@
+package protocol
+
+type FetchPartition struct {
+	Partition          int32
+	CurrentLeaderEpoch int32
+	FetchOffset        int64
+	LastFetchedEpoch   int32
+	LogStartOffset     int64
+	MaxBytes           int32
+}
+
+type FetchTopic struct {
+	Topic      string
+	Partitions []*FetchPartition
+}
+
+type ForgottenTopic struct {
+	Topic      string
+	Partitions []int32
+}
+
+type FetchRequest struct {
+	APIVersion int16
+
+	ReplicaID       int32
+	MaxWaitTime     int32
+	MinBytes        int32
+	MaxBytes        int32
+	IsolationLevel  int8
+	SessionID       int32
+	SessionEpoch    int32
+	Topics          []*FetchTopic
+	ForgottenTopics []*ForgottenTopic
+	RackID          string
+}
+
+func (r *FetchRequest) Encode(e PacketEncoder) (err error) {
+	flexible := r.APIVersion >= 12
+	e.PutInt32(r.ReplicaID)
+	e.PutInt32(r.MaxWaitTime)
+	e.PutInt32(r.MinBytes)
+	if r.APIVersion >= 3 {
+		e.PutInt32(r.MaxBytes)
+	}
+	if r.APIVersion >= 4 {
+		e.PutInt8(r.IsolationLevel)
+	}
+	if r.APIVersion >= 7 {
+		e.PutInt32(r.SessionID)
+		e.PutInt32(r.SessionEpoch)
+	}
+	if err = putArrayLength(e, len(r.Topics), flexible); err != nil {
+		return err
+	}
+	for _, t := range r.Topics {
+		if err = putString(e, t.Topic, flexible); err != nil {
+			return err
+		}
+		if err = putArrayLength(e, len(t.Partitions), flexible); err != nil {
+			return err
+		}
+		for _, p := range t.Partitions {
+			e.PutInt32(p.Partition)
+			if r.APIVersion >= 9 {
+				e.PutInt32(p.CurrentLeaderEpoch)
+			}
+			e.PutInt64(p.FetchOffset)
+			if r.APIVersion >= 12 {
+				e.PutInt32(p.LastFetchedEpoch)
+			}
+			if r.APIVersion >= 5 {
+				e.PutInt64(p.LogStartOffset)
+			}
+			e.PutInt32(p.MaxBytes)
+			if err = putTaggedFields(e, flexible); err != nil {
+				return err
+			}
+		}
+		if err = putTaggedFields(e, flexible); err != nil {
+			return err
+		}
+	}
+	if r.APIVersion >= 7 {
+		if err = putArrayLength(e, len(r.ForgottenTopics), flexible); err != nil {
+			return err
+		}
+		for _, t := range r.ForgottenTopics {
+			if err = putString(e, t.Topic, flexible); err != nil {
+				return err
+			}
+			if err = putInt32Array(e, t.Partitions, flexible); err != nil {
+				return err
+			}
+			if err = putTaggedFields(e, flexible); err != nil {
+				return err
+			}
+		}
+	}
+	if r.APIVersion >= 11 {
+		if err = putString(e, r.RackID, flexible); err != nil {
+			return err
+		}
+	}
+	return putTaggedFields(e, flexible)
+}
+
+func (r *FetchRequest) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	flexible := version >= 12
+
+	if r.ReplicaID, err = d.Int32(); err != nil {
+		return err
+	}
+	if r.MaxWaitTime, err = d.Int32(); err != nil {
+		return err
+	}
+	if r.MinBytes, err = d.Int32(); err != nil {
+		return err
+	}
+	if version >= 3 {
+		if r.MaxBytes, err = d.Int32(); err != nil {
+			return err
+		}
+	}
+	if version >= 4 {
+		if r.IsolationLevel, err = d.Int8(); err != nil {
+			return err
+		}
+	}
+	if version >= 7 {
+		if r.SessionID, err = d.Int32(); err != nil {
+			return err
+		}
+		if r.SessionEpoch, err = d.Int32(); err != nil {
+			return err
+		}
+	}
+	topicCount, err := getArrayLength(d, flexible)
+	if err != nil {
+		return err
+	}
+	r.Topics = make([]*FetchTopic, topicCount)
+	for i := range r.Topics {
+		t := &FetchTopic{}
+		if t.Topic, err = getString(d, flexible); err != nil {
+			return err
+		}
+		partitionCount, err := getArrayLength(d, flexible)
+		if err != nil {
+			return err
+		}
+		t.Partitions = make([]*FetchPartition, partitionCount)
+		for j := range t.Partitions {
+			p := &FetchPartition{CurrentLeaderEpoch: -1, LastFetchedEpoch: -1, LogStartOffset: -1}
+			if p.Partition, err = d.Int32(); err != nil {
+				return err
+			}
+			if version >= 9 {
+				if p.CurrentLeaderEpoch, err = d.Int32(); err != nil {
+					return err
+				}
+			}
+			if p.FetchOffset, err = d.Int64(); err != nil {
+				return err
+			}
+			if version >= 12 {
+				if p.LastFetchedEpoch, err = d.Int32(); err != nil {
+					return err
+				}
+			}
+			if version >= 5 {
+				if p.LogStartOffset, err = d.Int64(); err != nil {
+					return err
+				}
+			}
+			if p.MaxBytes, err = d.Int32(); err != nil {
+				return err
+			}
+			if err = skipTaggedFields(d, flexible); err != nil {
+				return err
+			}
+			t.Partitions[j] = p
+		}
+		if err = skipTaggedFields(d, flexible); err != nil {
+			return err
+		}
+		r.Topics[i] = t
+	}
+	if version >= 7 {
+		forgottenCount, err := getArrayLength(d, flexible)
+		if err != nil {
+			return err
+		}
+		r.ForgottenTopics = make([]*ForgottenTopic, forgottenCount)
+		for i := range r.ForgottenTopics {
+			t := &ForgottenTopic{}
+			if t.Topic, err = getString(d, flexible); err != nil {
+				return err
+			}
+			if t.Partitions, err = getInt32Array(d, flexible); err != nil {
+				return err
+			}
+			if err = skipTaggedFields(d, flexible); err != nil {
+				return err
+			}
+			r.ForgottenTopics[i] = t
+		}
+	}
+	if version >= 11 {
+		if r.RackID, err = getString(d, flexible); err != nil {
+			return err
+		}
+	}
+	return skipTaggedFields(d, flexible)
+}
+
+func (r *FetchRequest) Key() int16 {
+	return FetchKey
+}
+
+func (r *FetchRequest) Version() int16 {
+	return r.APIVersion
+}
@@ -0,0 +1,193 @@
+package protocol
+
+import "time"
+
+type AbortedTransaction struct {
+	ProducerID  int64
+	FirstOffset int64
+}
+
+type FetchPartitionResponse struct {
+	Partition            int32
+	ErrorCode            int16
+	HighWatermark        int64
+	LastStableOffset     int64
+	LogStartOffset       int64
+	AbortedTransactions  []*AbortedTransaction
+	PreferredReadReplica int32
+	Records              []byte
+}
+
+type FetchTopicResponse struct {
+	Topic              string
+	PartitionResponses []*FetchPartitionResponse
+}
+
+type FetchResponse struct {
+	APIVersion int16
+
+	ThrottleTime time.Duration
+	ErrorCode    int16
+	SessionID    int32
+	Responses    []*FetchTopicResponse
+}
+
+func (r *FetchResponse) Encode(e PacketEncoder) (err error) {
+	flexible := r.APIVersion >= 12
+	if r.APIVersion >= 1 {
+		e.PutInt32(int32(r.ThrottleTime / time.Millisecond))
+	}
+	if r.APIVersion >= 7 {
+		e.PutInt16(r.ErrorCode)
+		e.PutInt32(r.SessionID)
+	}
+	if err = putArrayLength(e, len(r.Responses), flexible); err != nil {
+		return err
+	}
+	for _, resp := range r.Responses {
+		if err = putString(e, resp.Topic, flexible); err != nil {
+			return err
+		}
+		if err = putArrayLength(e, len(resp.PartitionResponses), flexible); err != nil {
+			return err
+		}
+		for _, p := range resp.PartitionResponses {
+			e.PutInt32(p.Partition)
+			e.PutInt16(p.ErrorCode)
+			e.PutInt64(p.HighWatermark)
+			if r.APIVersion >= 4 {
+				e.PutInt64(p.LastStableOffset)
+			}
+			if r.APIVersion >= 5 {
+				e.PutInt64(p.LogStartOffset)
+			}
+			if r.APIVersion >= 4 {
+				if err = putArrayLength(e, len(p.AbortedTransactions), flexible); err != nil {
+					return err
+				}
+				for _, t := range p.AbortedTransactions {
+					e.PutInt64(t.ProducerID)
+					e.PutInt64(t.FirstOffset)
+					if err = putTaggedFields(e, flexible); err != nil {
+						return err
+					}
+				}
+			}
+			if r.APIVersion >= 11 {
+				e.PutInt32(p.PreferredReadReplica)
+			}
+			if err = putBytes(e, p.Records, flexible); err != nil {
+				return err
+			}
+			if err = putTaggedFields(e, flexible); err != nil {
+				return err
+			}
+		}
+		if err = putTaggedFields(e, flexible); err != nil {
+			return err
+		}
+	}
+	return putTaggedFields(e, flexible)
+}
+
+func (r *FetchResponse) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	flexible := version >= 12
+
+	if version >= 1 {
+		throttle, err := d.Int32()
+		if err != nil {
+			return err
+		}
+		r.ThrottleTime = time.Duration(throttle) * time.Millisecond
+	}
+	if version >= 7 {
+		if r.ErrorCode, err = d.Int16(); err != nil {
+			return err
+		}
+		if r.SessionID, err = d.Int32(); err != nil {
+			return err
+		}
+	}
+	l, err := getArrayLength(d, flexible)
+	if err != nil {
+		return err
+	}
+	r.Responses = make([]*FetchTopicResponse, l)
+	for i := range r.Responses {
+		resp := new(FetchTopicResponse)
+		r.Responses[i] = resp
+		if resp.Topic, err = getString(d, flexible); err != nil {
+			return err
+		}
+		pl, err := getArrayLength(d, flexible)
+		if err != nil {
+			return err
+		}
+		resp.PartitionResponses = make([]*FetchPartitionResponse, pl)
+		for j := range resp.PartitionResponses {
+			p := &FetchPartitionResponse{LastStableOffset: -1, LogStartOffset: -1, PreferredReadReplica: -1}
+			resp.PartitionResponses[j] = p
+			if p.Partition, err = d.Int32(); err != nil {
+				return err
+			}
+			if p.ErrorCode, err = d.Int16(); err != nil {
+				return err
+			}
+			if p.HighWatermark, err = d.Int64(); err != nil {
+				return err
+			}
+			if version >= 4 {
+				if p.LastStableOffset, err = d.Int64(); err != nil {
+					return err
+				}
+			}
+			if version >= 5 {
+				if p.LogStartOffset, err = d.Int64(); err != nil {
+					return err
+				}
+			}
+			if version >= 4 {
+				abortedCount, err := getNullableArrayLength(d, flexible)
+				if err != nil {
+					return err
+				}
+				if abortedCount > 0 {
+					p.AbortedTransactions = make([]*AbortedTransaction, abortedCount)
+				}
+				for k := range p.AbortedTransactions {
+					t := new(AbortedTransaction)
+					if t.ProducerID, err = d.Int64(); err != nil {
+						return err
+					}
+					if t.FirstOffset, err = d.Int64(); err != nil {
+						return err
+					}
+					if err = skipTaggedFields(d, flexible); err != nil {
+						return err
+					}
+					p.AbortedTransactions[k] = t
+				}
+			}
+			if version >= 11 {
+				if p.PreferredReadReplica, err = d.Int32(); err != nil {
+					return err
+				}
+			}
+			if p.Records, err = getBytes(d, flexible); err != nil {
+				return err
+			}
+			if err = skipTaggedFields(d, flexible); err != nil {
+				return err
+			}
+		}
+		if err = skipTaggedFields(d, flexible); err != nil {
+			return err
+		}
+	}
+	return skipTaggedFields(d, flexible)
+}
+
+func (r *FetchResponse) Version() int16 {
+	return r.APIVersion
+}
@@ -0,0 +1,63 @@
+package protocol
+
+import "time"
+
+type FindCoordinatorResponse struct {
+	APIVersion int16
+
+	ThrottleTime time.Duration
+	ErrorCode    int16
+	ErrorMessage *string
+	NodeID       int32
+	Host         string
+	Port         int32
+}
+
+func (r *FindCoordinatorResponse) Encode(e PacketEncoder) (err error) {
+	if r.APIVersion >= 1 {
+		e.PutInt32(int32(r.ThrottleTime / time.Millisecond))
+	}
+	e.PutInt16(r.ErrorCode)
+	if r.APIVersion >= 1 {
+		if err = e.PutNullableString(r.ErrorMessage); err != nil {
+			return err
+		}
+	}
+	e.PutInt32(r.NodeID)
+	if err = e.PutString(r.Host); err != nil {
+		return err
+	}
+	e.PutInt32(r.Port)
+	return nil
+}
+
+func (r *FindCoordinatorResponse) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	if version >= 1 {
+		throttle, err := d.Int32()
+		if err != nil {
+			return err
+		}
+		r.ThrottleTime = time.Duration(throttle) * time.Millisecond
+	}
+	if r.ErrorCode, err = d.Int16(); err != nil {
+		return err
+	}
+	if version >= 1 {
+		if r.ErrorMessage, err = d.NullableString(); err != nil {
+			return err
+		}
+	}
+	if r.NodeID, err = d.Int32(); err != nil {
+		return err
+	}
+	if r.Host, err = d.String(); err != nil {
+		return err
+	}
+	r.Port, err = d.Int32()
+	return err
+}
+
+func (r *FindCoordinatorResponse) Version() int16 {
+	return r.APIVersion
+}
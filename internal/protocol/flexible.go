@@ -0,0 +1,205 @@
+package protocol
+
+// flexibleVersions are the first versions of the APIs using the flexible encoding,
+// with compact strings, bytes and arrays and tagged fields. The APIs missing from
+// the map up to DescribeTopicPartitions have no flexible version.
+var flexibleVersions = map[int16]int16{
+	ProduceKey:                      9,
+	FetchKey:                        12,
+	OffsetsKey:                      6,
+	MetadataKey:                     9,
+	LeaderAndISRKey:                 4,
+	StopReplicaKey:                  2,
+	UpdateMetadataKey:               6,
+	ControlledShutdownKey:           3,
+	OffsetCommitKey:                 8,
+	OffsetFetchKey:                  6,
+	FindCoordinatorKey:              3,
+	JoinGroupKey:                    6,
+	HeartbeatKey:                    4,
+	LeaveGroupKey:                   4,
+	SyncGroupKey:                    4,
+	DescribeGroupsKey:               5,
+	ListGroupsKey:                   3,
+	APIVersionsKey:                  3,
+	CreateTopicsKey:                 5,
+	DeleteTopicsKey:                 4,
+	DeleteRecordsKey:                2,
+	InitProducerIDKey:               2,
+	OffsetForLeaderEpochKey:         4,
+	AddPartitionsToTxnKey:           3,
+	AddOffsetsToTxnKey:              3,
+	EndTxnKey:                       3,
+	WriteTxnMarkersKey:              1,
+	TxnOffsetCommitKey:              3,
+	DescribeAclsKey:                 2,
+	CreateAclsKey:                   2,
+	DeleteAclsKey:                   2,
+	DescribeConfigsKey:              4,
+	AlterConfigsKey:                 2,
+	AlterReplicaLogDirsKey:          2,
+	DescribeLogDirsKey:              2,
+	SaslAuthenticateKey:             2,
+	CreatePartitionsKey:             2,
+	CreateDelegationTokenKey:        2,
+	RenewDelegationTokenKey:         2,
+	ExpireDelegationTokenKey:        2,
+	DescribeDelegationTokenKey:      2,
+	DeleteGroupsKey:                 2,
+	ElectLeadersKey:                 2,
+	IncrementalAlterConfigsKey:      1,
+	AlterPartitionReassignmentsKey:  0,
+	ListPartitionReassignmentsKey:   0,
+	DescribeClientQuotasKey:         1,
+	AlterClientQuotasKey:            1,
+	DescribeUserScramCredentialsKey: 0,
+	AlterUserScramCredentialsKey:    0,
+	VoteKey:                         0,
+	DescribeQuorumKey:               0,
+	AlterPartitionKey:               0,
+	UpdateFeaturesKey:               0,
+	EnvelopeKey:                     0,
+	FetchSnapshotKey:                0,
+	DescribeClusterKey:              0,
+	DescribeProducersKey:            0,
+	BrokerRegistrationKey:           0,
+	BrokerHeartbeatKey:              0,
+	UnregisterBrokerKey:             0,
+	DescribeTransactionsKey:         0,
+	ListTransactionsKey:             0,
+	AllocateProducerIDsKey:          0,
+	ConsumerGroupHeartbeatKey:       0,
+	ConsumerGroupDescribeKey:        0,
+	ControllerRegistrationKey:       0,
+	GetTelemetrySubscriptionsKey:    0,
+	PushTelemetryKey:                0,
+	AssignReplicasToDirsKey:         0,
+	ListClientMetricsResourcesKey:   0,
+	DescribeTopicPartitionsKey:      0,
+}
+
+// Flexible reports whether a version of an API uses the flexible encoding. The APIs
+// added after the map are assumed flexible, like every API added since KIP-482.
+func Flexible(key, version int16) bool {
+	if key > DescribeTopicPartitionsKey {
+		return true
+	}
+	first, ok := flexibleVersions[key]
+	return ok && version >= first
+}
+
+// The helpers below write and read a field in the encoding of a version, compact
+// when flexible is set and classic otherwise.
+
+// putArrayLength writes the length of an array, -1 for a null array
+func putArrayLength(e PacketEncoder, in int, flexible bool) error {
+	if flexible {
+		return e.PutCompactArrayLength(in)
+	}
+	if in == -1 {
+		e.PutInt32(-1)
+		return nil
+	}
+	return e.PutArrayLength(in)
+}
+
+func putBytes(e PacketEncoder, in []byte, flexible bool) error {
+	if flexible {
+		return e.PutCompactBytes(in)
+	}
+	return e.PutBytes(in)
+}
+
+func putString(e PacketEncoder, in string, flexible bool) error {
+	if flexible {
+		return e.PutCompactString(in)
+	}
+	return e.PutString(in)
+}
+
+func putNullableString(e PacketEncoder, in *string, flexible bool) error {
+	if flexible {
+		return e.PutCompactNullableString(in)
+	}
+	return e.PutNullableString(in)
+}
+
+func putInt32Array(e PacketEncoder, in []int32, flexible bool) error {
+	if flexible {
+		return e.PutCompactInt32Array(in)
+	}
+	return e.PutInt32Array(in)
+}
+
+// putTaggedFields ends a structure with an empty tagged fields section
+func putTaggedFields(e PacketEncoder, flexible bool) error {
+	if flexible {
+		return e.PutTaggedFields(nil)
+	}
+	return nil
+}
+
+// getArrayLength reads the length of an array which is not nullable, a null compact
+// array is read as an empty one
+func getArrayLength(d PacketDecoder, flexible bool) (int, error) {
+	if !flexible {
+		return d.ArrayLength()
+	}
+	n, err := d.CompactArrayLength()
+	if n == -1 {
+		return 0, err
+	}
+	return n, err
+}
+
+// getNullableArrayLength reads the length of an array, -1 for a null array
+func getNullableArrayLength(d PacketDecoder, flexible bool) (int, error) {
+	if flexible {
+		return d.CompactArrayLength()
+	}
+	n, err := d.Int32()
+	if err != nil || n == -1 {
+		return -1, err
+	}
+	if n < -1 || int(n) > d.remaining() {
+		return -1, ErrInvalidArrayLength
+	}
+	return int(n), nil
+}
+
+func getBytes(d PacketDecoder, flexible bool) ([]byte, error) {
+	if flexible {
+		return d.CompactBytes()
+	}
+	return d.Bytes()
+}
+
+func getString(d PacketDecoder, flexible bool) (string, error) {
+	if flexible {
+		return d.CompactString()
+	}
+	return d.String()
+}
+
+func getNullableString(d PacketDecoder, flexible bool) (*string, error) {
+	if flexible {
+		return d.CompactNullableString()
+	}
+	return d.NullableString()
+}
+
+func getInt32Array(d PacketDecoder, flexible bool) ([]int32, error) {
+	if flexible {
+		return d.CompactInt32Array()
+	}
+	return d.Int32Array()
+}
+
+// skipTaggedFields skips the tagged fields ending a structure, none of them is used
+func skipTaggedFields(d PacketDecoder, flexible bool) error {
+	if flexible {
+		_, err := d.TaggedFields()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package protocol
+
+import "testing"
+
+// Requests sent by franz-go 1.17.1 to the mock, without their size
+const (
+	apiVersionsV3Fixture = "001200030000000000076669787475726500" +
+		"096672616e7a2d676f07312e31372e3100"
+	metadataV9Fixture = "00030009000000010007666978747572650002056d6f636b0000000000"
+	produceV9Fixture  = "000000090000000100076669787475726500" +
+		"00ffff0000271002056d6f636b02000000004700000000000000000000003affffffff02cc89d1ce0000000000000000018bcfe568000000018bcfe56800ffffffffffffffffffff000000000000000110000000026b027600000000"
+	listOffsetsV6Fixture = "000200060000000000076669787475726500" +
+		"ffffffff0002056d6f636b0200000000fffffffffffffffffffffffe000000"
+	listOffsetsV7Fixture = "000200070000000000076669787475726500" +
+		"ffffffff0002056d6f636b0200000000fffffffffffffffffffffffe000000"
+	fetchV12Fixture = "0001000c0000000000076669787475726500" +
+		"ffffffff00001388000000010320000000000000000000000002056d6f636b0200000000ffffffff0000000000000000ffffffffffffffffffffffff001000000000010100"
+)
+
+// decodeFixture decodes the header of a request fixture and returns the decoder of its body
+func decodeFixture(t *testing.T, fixture string, key, version int16) *ByteDecoder {
+	t.Helper()
+	frame := mustHex(t, fixture)
+	b := make([]byte, 4+len(frame))
+	Encoding.PutUint32(b, uint32(len(frame)))
+	copy(b[4:], frame)
+
+	d := NewDecoder(b)
+	header := &RequestHeader{}
+	if err := header.Decode(d); err != nil {
+		t.Fatal(err)
+	}
+	if header.APIKey != key || header.APIVersion != version || header.ClientID != "fixture" {
+		t.Fatalf("decoded header %+v", header)
+	}
+	if !header.Flexible() || (key != APIVersionsKey && header.ResponseHeaderVersion() != 1) {
+		t.Fatalf("request %d v%d not flexible", key, version)
+	}
+	return d
+}
+
+// checkDecoded fails unless the whole request was decoded
+func checkDecoded(t *testing.T, d *ByteDecoder, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.remaining() != 0 {
+		t.Fatalf("%d bytes left", d.remaining())
+	}
+}
+
+func TestDecodeApiVersionsV3(t *testing.T) {
+	d := decodeFixture(t, apiVersionsV3Fixture, APIVersionsKey, 3)
+	req := &APIVersionsRequest{}
+	checkDecoded(t, d, req.Decode(d, 3))
+	if req.ClientSoftwareName != "franz-go" || req.ClientSoftwareVersion != "1.17.1" {
+		t.Fatalf("decoded %+v", req)
+	}
+}
+
+func TestDecodeMetadataV9(t *testing.T) {
+	d := decodeFixture(t, metadataV9Fixture, MetadataKey, 9)
+	req := &MetadataRequest{}
+	checkDecoded(t, d, req.Decode(d, 9))
+	if len(req.Topics) != 1 || req.Topics[0] != "mock" || req.AllowAutoTopicCreation {
+		t.Fatalf("decoded %+v", req)
+	}
+}
+
+func TestDecodeProduceV9(t *testing.T) {
+	d := decodeFixture(t, produceV9Fixture, ProduceKey, 9)
+	req := &ProduceRequest{}
+	checkDecoded(t, d, req.Decode(d, 9))
+	if req.TransactionalID != nil || req.RequiredAcks != -1 || req.Timeout != 10000 {
+		t.Fatalf("decoded %+v", req)
+	}
+	records, ok := req.Records["mock"][0]
+	if !ok || records.RecordBatch == nil {
+		t.Fatalf("decoded records %+v", req.Records)
+	}
+	batch := records.RecordBatch
+	if len(batch.Records) != 1 || string(batch.Records[0].Key) != "k" || string(batch.Records[0].Value) != "v" {
+		t.Fatalf("decoded batch %+v", batch)
+	}
+	if batch.FirstTimestamp.Unix() != 1700000000 {
+		t.Fatalf("decoded timestamp %s", batch.FirstTimestamp)
+	}
+}
+
+func TestDecodeListOffsetsFlexible(t *testing.T) {
+	for version, fixture := range map[int16]string{6: listOffsetsV6Fixture, 7: listOffsetsV7Fixture} {
+		d := decodeFixture(t, fixture, OffsetsKey, version)
+		req := &ListOffsetsRequest{}
+		checkDecoded(t, d, req.Decode(d, version))
+		if req.ReplicaID != -1 || req.IsolationLevel != ReadUncommitted || len(req.Topics) != 1 || req.Topics[0].Topic != "mock" {
+			t.Fatalf("v%d decoded %+v", version, req)
+		}
+		p := req.Topics[0].Partitions
+		if len(p) != 1 || p[0].Partition != 0 || p[0].CurrentLeaderEpoch != -1 || p[0].Timestamp != EarliestTimestamp {
+			t.Fatalf("v%d decoded partitions %+v", version, p)
+		}
+	}
+}
+
+func TestDecodeFetchV12(t *testing.T) {
+	d := decodeFixture(t, fetchV12Fixture, FetchKey, 12)
+	req := &FetchRequest{}
+	checkDecoded(t, d, req.Decode(d, 12))
+	if req.ReplicaID != -1 || req.MaxWaitTime != 5000 || req.MinBytes != 1 || req.MaxBytes != 52428800 || req.RackID != "" {
+		t.Fatalf("decoded %+v", req)
+	}
+	if len(req.Topics) != 1 || req.Topics[0].Topic != "mock" || len(req.Topics[0].Partitions) != 1 {
+		t.Fatalf("decoded topics %+v", req.Topics)
+	}
+	p := req.Topics[0].Partitions[0]
+	if p.FetchOffset != 0 || p.CurrentLeaderEpoch != -1 || p.LastFetchedEpoch != -1 || p.LogStartOffset != -1 || p.MaxBytes != 1048576 {
+		t.Fatalf("decoded partition %+v", p)
+	}
+}
+
+func TestFlexibleVersions(t *testing.T) {
+	tests := []struct {
+		key, version int16
+		flexible     bool
+	}{
+		{ProduceKey, 8, false},
+		{ProduceKey, 9, true},
+		{SaslHandshakeKey, 1, false},
+		{OffsetDeleteKey, 0, false},
+		{ElectLeadersKey, 1, false},
+		{ElectLeadersKey, 2, true},
+		{DescribeClusterKey, 0, true},
+		{DescribeTopicPartitionsKey, 0, true},
+		// APIs added after the table
+		{DescribeTopicPartitionsKey + 1, 0, true},
+	}
+	for _, tt := range tests {
+		if got := Flexible(tt.key, tt.version); got != tt.flexible {
+			t.Errorf("Flexible(%d, %d) = %t, want %t", tt.key, tt.version, got, tt.flexible)
+		}
+	}
+}
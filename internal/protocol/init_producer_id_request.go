@@ -0,0 +1,40 @@
+package protocol
+
+import "time"
+
+type InitProducerIDRequest struct {
+	APIVersion int16
+
+	// TransactionalID is null for idempotent producers without transactions
+	TransactionalID    *string
+	TransactionTimeout time.Duration
+}
+
+func (r *InitProducerIDRequest) Encode(e PacketEncoder) (err error) {
+	if err = e.PutNullableString(r.TransactionalID); err != nil {
+		return err
+	}
+	e.PutInt32(int32(r.TransactionTimeout / time.Millisecond))
+	return nil
+}
+
+func (r *InitProducerIDRequest) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	if r.TransactionalID, err = d.NullableString(); err != nil {
+		return err
+	}
+	timeout, err := d.Int32()
+	if err != nil {
+		return err
+	}
+	r.TransactionTimeout = time.Duration(timeout) * time.Millisecond
+	return nil
+}
+
+func (r *InitProducerIDRequest) Key() int16 {
+	return InitProducerIDKey
+}
+
+func (r *InitProducerIDRequest) Version() int16 {
+	return r.APIVersion
+}
@@ -0,0 +1,41 @@
+package protocol
+
+import "time"
+
+type InitProducerIDResponse struct {
+	APIVersion int16
+
+	ThrottleTime  time.Duration
+	ErrorCode     int16
+	ProducerID    int64
+	ProducerEpoch int16
+}
+
+func (r *InitProducerIDResponse) Encode(e PacketEncoder) (err error) {
+	e.PutInt32(int32(r.ThrottleTime / time.Millisecond))
+	e.PutInt16(r.ErrorCode)
+	e.PutInt64(r.ProducerID)
+	e.PutInt16(r.ProducerEpoch)
+	return nil
+}
+
+func (r *InitProducerIDResponse) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	throttle, err := d.Int32()
+	if err != nil {
+		return err
+	}
+	r.ThrottleTime = time.Duration(throttle) * time.Millisecond
+	if r.ErrorCode, err = d.Int16(); err != nil {
+		return err
+	}
+	if r.ProducerID, err = d.Int64(); err != nil {
+		return err
+	}
+	r.ProducerEpoch, err = d.Int16()
+	return err
+}
+
+func (r *InitProducerIDResponse) Version() int16 {
+	return r.APIVersion
+}
@@ -0,0 +1,103 @@
+package protocol
+
+type GroupProtocol struct {
+	Name     string
+	Metadata []byte
+}
+
+type JoinGroupRequest struct {
+	APIVersion int16
+
+	GroupID          string
+	SessionTimeout   int32
+	RebalanceTimeout int32
+	MemberID         string
+	GroupInstanceID  *string
+	ProtocolType     string
+	GroupProtocols   []*GroupProtocol
+}
+
+func (r *JoinGroupRequest) Encode(e PacketEncoder) (err error) {
+	if err = e.PutString(r.GroupID); err != nil {
+		return err
+	}
+	e.PutInt32(r.SessionTimeout)
+	if r.APIVersion >= 1 {
+		e.PutInt32(r.RebalanceTimeout)
+	}
+	if err = e.PutString(r.MemberID); err != nil {
+		return err
+	}
+	if r.APIVersion >= 5 {
+		if err = e.PutNullableString(r.GroupInstanceID); err != nil {
+			return err
+		}
+	}
+	if err = e.PutString(r.ProtocolType); err != nil {
+		return err
+	}
+	if err = e.PutArrayLength(len(r.GroupProtocols)); err != nil {
+		return err
+	}
+	for _, p := range r.GroupProtocols {
+		if err = e.PutString(p.Name); err != nil {
+			return err
+		}
+		if err = e.PutBytes(p.Metadata); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *JoinGroupRequest) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	if r.GroupID, err = d.String(); err != nil {
+		return err
+	}
+	if r.SessionTimeout, err = d.Int32(); err != nil {
+		return err
+	}
+	// Version 0 uses the session timeout for rebalances as well
+	r.RebalanceTimeout = r.SessionTimeout
+	if version >= 1 {
+		if r.RebalanceTimeout, err = d.Int32(); err != nil {
+			return err
+		}
+	}
+	if r.MemberID, err = d.String(); err != nil {
+		return err
+	}
+	if version >= 5 {
+		if r.GroupInstanceID, err = d.NullableString(); err != nil {
+			return err
+		}
+	}
+	if r.ProtocolType, err = d.String(); err != nil {
+		return err
+	}
+	n, err := d.ArrayLength()
+	if err != nil {
+		return err
+	}
+	r.GroupProtocols = make([]*GroupProtocol, n)
+	for i := range r.GroupProtocols {
+		p := &GroupProtocol{}
+		if p.Name, err = d.String(); err != nil {
+			return err
+		}
+		if p.Metadata, err = d.Bytes(); err != nil {
+			return err
+		}
+		r.GroupProtocols[i] = p
+	}
+	return nil
+}
+
+func (r *JoinGroupRequest) Key() int16 {
+	return JoinGroupKey
+}
+
+func (r *JoinGroupRequest) Version() int16 {
+	return r.APIVersion
+}
@@ -0,0 +1,106 @@
+package protocol
+
+import "time"
+
+type GroupMember struct {
+	MemberID        string
+	GroupInstanceID *string
+	Metadata        []byte
+}
+
+type JoinGroupResponse struct {
+	APIVersion int16
+
+	ThrottleTime  time.Duration
+	ErrorCode     int16
+	GenerationID  int32
+	GroupProtocol string
+	LeaderID      string
+	MemberID      string
+	Members       []*GroupMember
+}
+
+func (r *JoinGroupResponse) Encode(e PacketEncoder) (err error) {
+	if r.APIVersion >= 2 {
+		e.PutInt32(int32(r.ThrottleTime / time.Millisecond))
+	}
+	e.PutInt16(r.ErrorCode)
+	e.PutInt32(r.GenerationID)
+	if err = e.PutString(r.GroupProtocol); err != nil {
+		return err
+	}
+	if err = e.PutString(r.LeaderID); err != nil {
+		return err
+	}
+	if err = e.PutString(r.MemberID); err != nil {
+		return err
+	}
+	if err = e.PutArrayLength(len(r.Members)); err != nil {
+		return err
+	}
+	for _, m := range r.Members {
+		if err = e.PutString(m.MemberID); err != nil {
+			return err
+		}
+		if r.APIVersion >= 5 {
+			if err = e.PutNullableString(m.GroupInstanceID); err != nil {
+				return err
+			}
+		}
+		if err = e.PutBytes(m.Metadata); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *JoinGroupResponse) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	if version >= 2 {
+		throttle, err := d.Int32()
+		if err != nil {
+			return err
+		}
+		r.ThrottleTime = time.Duration(throttle) * time.Millisecond
+	}
+	if r.ErrorCode, err = d.Int16(); err != nil {
+		return err
+	}
+	if r.GenerationID, err = d.Int32(); err != nil {
+		return err
+	}
+	if r.GroupProtocol, err = d.String(); err != nil {
+		return err
+	}
+	if r.LeaderID, err = d.String(); err != nil {
+		return err
+	}
+	if r.MemberID, err = d.String(); err != nil {
+		return err
+	}
+	n, err := d.ArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Members = make([]*GroupMember, n)
+	for i := range r.Members {
+		m := &GroupMember{}
+		if m.MemberID, err = d.String(); err != nil {
+			return err
+		}
+		if version >= 5 {
+			if m.GroupInstanceID, err = d.NullableString(); err != nil {
+				return err
+			}
+		}
+		if m.Metadata, err = d.Bytes(); err != nil {
+			return err
+		}
+		r.Members[i] = m
+	}
+	return nil
+}
+
+func (r *JoinGroupResponse) Version() int16 {
+	return r.APIVersion
+}
@@ -0,0 +1,71 @@
+package protocol
+
+type MemberIdentity struct {
+	MemberID        string
+	GroupInstanceID *string
+}
+
+type LeaveGroupRequest struct {
+	APIVersion int16
+
+	GroupID string
+	// MemberID is used by versions before 3, which can only remove one member
+	MemberID string
+	Members  []*MemberIdentity
+}
+
+func (r *LeaveGroupRequest) Encode(e PacketEncoder) (err error) {
+	if err = e.PutString(r.GroupID); err != nil {
+		return err
+	}
+	if r.APIVersion < 3 {
+		return e.PutString(r.MemberID)
+	}
+	if err = e.PutArrayLength(len(r.Members)); err != nil {
+		return err
+	}
+	for _, m := range r.Members {
+		if err = e.PutString(m.MemberID); err != nil {
+			return err
+		}
+		if err = e.PutNullableString(m.GroupInstanceID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *LeaveGroupRequest) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	if r.GroupID, err = d.String(); err != nil {
+		return err
+	}
+	if version < 3 {
+		r.MemberID, err = d.String()
+		return err
+	}
+	n, err := d.ArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Members = make([]*MemberIdentity, n)
+	for i := range r.Members {
+		m := &MemberIdentity{}
+		if m.MemberID, err = d.String(); err != nil {
+			return err
+		}
+		if m.GroupInstanceID, err = d.NullableString(); err != nil {
+			return err
+		}
+		r.Members[i] = m
+	}
+	return nil
+}
+
+func (r *LeaveGroupRequest) Key() int16 {
+	return LeaveGroupKey
+}
+
+func (r *LeaveGroupRequest) Version() int16 {
+	return r.APIVersion
+}
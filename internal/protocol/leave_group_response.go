@@ -0,0 +1,78 @@
+package protocol
+
+import "time"
+
+type LeaveGroupMemberResponse struct {
+	MemberID        string
+	GroupInstanceID *string
+	ErrorCode       int16
+}
+
+type LeaveGroupResponse struct {
+	APIVersion int16
+
+	ThrottleTime time.Duration
+	ErrorCode    int16
+	Members      []*LeaveGroupMemberResponse
+}
+
+func (r *LeaveGroupResponse) Encode(e PacketEncoder) (err error) {
+	if r.APIVersion >= 1 {
+		e.PutInt32(int32(r.ThrottleTime / time.Millisecond))
+	}
+	e.PutInt16(r.ErrorCode)
+	if r.APIVersion >= 3 {
+		if err = e.PutArrayLength(len(r.Members)); err != nil {
+			return err
+		}
+		for _, m := range r.Members {
+			if err = e.PutString(m.MemberID); err != nil {
+				return err
+			}
+			if err = e.PutNullableString(m.GroupInstanceID); err != nil {
+				return err
+			}
+			e.PutInt16(m.ErrorCode)
+		}
+	}
+	return nil
+}
+
+func (r *LeaveGroupResponse) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	if version >= 1 {
+		throttle, err := d.Int32()
+		if err != nil {
+			return err
+		}
+		r.ThrottleTime = time.Duration(throttle) * time.Millisecond
+	}
+	if r.ErrorCode, err = d.Int16(); err != nil {
+		return err
+	}
+	if version >= 3 {
+		n, err := d.ArrayLength()
+		if err != nil {
+			return err
+		}
+		r.Members = make([]*LeaveGroupMemberResponse, n)
+		for i := range r.Members {
+			m := &LeaveGroupMemberResponse{}
+			if m.MemberID, err = d.String(); err != nil {
+				return err
+			}
+			if m.GroupInstanceID, err = d.NullableString(); err != nil {
+				return err
+			}
+			if m.ErrorCode, err = d.Int16(); err != nil {
+				return err
+			}
+			r.Members[i] = m
+		}
+	}
+	return nil
+}
+
+func (r *LeaveGroupResponse) Version() int16 {
+	return r.APIVersion
+}
@@ -0,0 +1,133 @@
+package protocol
+
+const (
+	// Special timestamps of ListOffsets requests
+	LatestTimestamp   int64 = -1
+	EarliestTimestamp int64 = -2
+	MaxTimestamp      int64 = -3
+
+	ReadUncommitted int8 = 0
+	ReadCommitted   int8 = 1
+)
+
+type ListOffsetsPartition struct {
+	Partition          int32
+	CurrentLeaderEpoch int32
+	Timestamp          int64
+	// MaxNumOffsets is only sent by version 0
+	MaxNumOffsets int32
+}
+
+type ListOffsetsTopic struct {
+	Topic      string
+	Partitions []*ListOffsetsPartition
+}
+
+type ListOffsetsRequest struct {
+	APIVersion int16
+
+	ReplicaID      int32
+	IsolationLevel int8
+	Topics         []*ListOffsetsTopic
+}
+
+func (r *ListOffsetsRequest) Encode(e PacketEncoder) (err error) {
+	flexible := r.APIVersion >= 6
+	e.PutInt32(r.ReplicaID)
+	if r.APIVersion >= 2 {
+		e.PutInt8(r.IsolationLevel)
+	}
+	if err = putArrayLength(e, len(r.Topics), flexible); err != nil {
+		return err
+	}
+	for _, t := range r.Topics {
+		if err = putString(e, t.Topic, flexible); err != nil {
+			return err
+		}
+		if err = putArrayLength(e, len(t.Partitions), flexible); err != nil {
+			return err
+		}
+		for _, p := range t.Partitions {
+			e.PutInt32(p.Partition)
+			if r.APIVersion >= 4 {
+				e.PutInt32(p.CurrentLeaderEpoch)
+			}
+			e.PutInt64(p.Timestamp)
+			if r.APIVersion == 0 {
+				e.PutInt32(p.MaxNumOffsets)
+			}
+			if err = putTaggedFields(e, flexible); err != nil {
+				return err
+			}
+		}
+		if err = putTaggedFields(e, flexible); err != nil {
+			return err
+		}
+	}
+	return putTaggedFields(e, flexible)
+}
+
+func (r *ListOffsetsRequest) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	flexible := version >= 6
+	if r.ReplicaID, err = d.Int32(); err != nil {
+		return err
+	}
+	if version >= 2 {
+		if r.IsolationLevel, err = d.Int8(); err != nil {
+			return err
+		}
+	}
+	n, err := getArrayLength(d, flexible)
+	if err != nil {
+		return err
+	}
+	r.Topics = make([]*ListOffsetsTopic, n)
+	for i := range r.Topics {
+		t := &ListOffsetsTopic{}
+		if t.Topic, err = getString(d, flexible); err != nil {
+			return err
+		}
+		pn, err := getArrayLength(d, flexible)
+		if err != nil {
+			return err
+		}
+		t.Partitions = make([]*ListOffsetsPartition, pn)
+		for j := range t.Partitions {
+			p := &ListOffsetsPartition{CurrentLeaderEpoch: -1, MaxNumOffsets: 1}
+			if p.Partition, err = d.Int32(); err != nil {
+				return err
+			}
+			if version >= 4 {
+				if p.CurrentLeaderEpoch, err = d.Int32(); err != nil {
+					return err
+				}
+			}
+			if p.Timestamp, err = d.Int64(); err != nil {
+				return err
+			}
+			if version == 0 {
+				if p.MaxNumOffsets, err = d.Int32(); err != nil {
+					return err
+				}
+			}
+			if err = skipTaggedFields(d, flexible); err != nil {
+				return err
+			}
+			t.Partitions[j] = p
+		}
+		if err = skipTaggedFields(d, flexible); err != nil {
+			return err
+		}
+		r.Topics[i] = t
+	}
+	return skipTaggedFields(d, flexible)
+}
+
+func (r *ListOffsetsRequest) Key() int16 {
+	return OffsetsKey
+}
+
+func (r *ListOffsetsRequest) Version() int16 {
+	return r.APIVersion
+}
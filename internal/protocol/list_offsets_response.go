@@ -0,0 +1,132 @@
+package protocol
+
+import "time"
+
+type ListOffsetsPartitionResponse struct {
+	Partition int32
+	ErrorCode int16
+	// OldStyleOffsets is only sent by version 0
+	OldStyleOffsets []int64
+	Timestamp       int64
+	Offset          int64
+	LeaderEpoch     int32
+}
+
+type ListOffsetsTopicResponse struct {
+	Topic      string
+	Partitions []*ListOffsetsPartitionResponse
+}
+
+type ListOffsetsResponse struct {
+	APIVersion int16
+
+	ThrottleTime time.Duration
+	Topics       []*ListOffsetsTopicResponse
+}
+
+func (r *ListOffsetsResponse) Encode(e PacketEncoder) (err error) {
+	flexible := r.APIVersion >= 6
+	if r.APIVersion >= 2 {
+		e.PutInt32(int32(r.ThrottleTime / time.Millisecond))
+	}
+	if err = putArrayLength(e, len(r.Topics), flexible); err != nil {
+		return err
+	}
+	for _, t := range r.Topics {
+		if err = putString(e, t.Topic, flexible); err != nil {
+			return err
+		}
+		if err = putArrayLength(e, len(t.Partitions), flexible); err != nil {
+			return err
+		}
+		for _, p := range t.Partitions {
+			e.PutInt32(p.Partition)
+			e.PutInt16(p.ErrorCode)
+			if r.APIVersion == 0 {
+				if err = e.PutInt64Array(p.OldStyleOffsets); err != nil {
+					return err
+				}
+				continue
+			}
+			e.PutInt64(p.Timestamp)
+			e.PutInt64(p.Offset)
+			if r.APIVersion >= 4 {
+				e.PutInt32(p.LeaderEpoch)
+			}
+			if err = putTaggedFields(e, flexible); err != nil {
+				return err
+			}
+		}
+		if err = putTaggedFields(e, flexible); err != nil {
+			return err
+		}
+	}
+	return putTaggedFields(e, flexible)
+}
+
+func (r *ListOffsetsResponse) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	flexible := version >= 6
+	if version >= 2 {
+		throttle, err := d.Int32()
+		if err != nil {
+			return err
+		}
+		r.ThrottleTime = time.Duration(throttle) * time.Millisecond
+	}
+	n, err := getArrayLength(d, flexible)
+	if err != nil {
+		return err
+	}
+	r.Topics = make([]*ListOffsetsTopicResponse, n)
+	for i := range r.Topics {
+		t := &ListOffsetsTopicResponse{}
+		if t.Topic, err = getString(d, flexible); err != nil {
+			return err
+		}
+		pn, err := getArrayLength(d, flexible)
+		if err != nil {
+			return err
+		}
+		t.Partitions = make([]*ListOffsetsPartitionResponse, pn)
+		for j := range t.Partitions {
+			p := &ListOffsetsPartitionResponse{Timestamp: -1, Offset: -1, LeaderEpoch: -1}
+			if p.Partition, err = d.Int32(); err != nil {
+				return err
+			}
+			if p.ErrorCode, err = d.Int16(); err != nil {
+				return err
+			}
+			t.Partitions[j] = p
+			if version == 0 {
+				if p.OldStyleOffsets, err = d.Int64Array(); err != nil {
+					return err
+				}
+				continue
+			}
+			if p.Timestamp, err = d.Int64(); err != nil {
+				return err
+			}
+			if p.Offset, err = d.Int64(); err != nil {
+				return err
+			}
+			if version >= 4 {
+				if p.LeaderEpoch, err = d.Int32(); err != nil {
+					return err
+				}
+			}
+			if err = skipTaggedFields(d, flexible); err != nil {
+				return err
+			}
+		}
+		if err = skipTaggedFields(d, flexible); err != nil {
+			return err
+		}
+		r.Topics[i] = t
+	}
+	return skipTaggedFields(d, flexible)
+}
+
+func (r *ListOffsetsResponse) Version() int16 {
+	return r.APIVersion
+}
@@ -3,31 +3,82 @@ package protocol
 type MetadataRequest struct {
 	APIVersion int16
 
-	Topics                 []string
-	AllowAutoTopicCreation bool
+	// Topics is null from version 1 on to ask for all topics, version 0 uses an empty array
+	Topics                             []string
+	AllowAutoTopicCreation             bool
+	IncludeClusterAuthorizedOperations bool
+	IncludeTopicAuthorizedOperations   bool
 }
 
 func (r *MetadataRequest) Encode(e PacketEncoder) (err error) {
-	err = e.PutStringArray(r.Topics)
-	if err != nil {
+	flexible := r.APIVersion >= 9
+	topicCount := len(r.Topics)
+	if r.Topics == nil && r.APIVersion >= 1 {
+		topicCount = -1
+	}
+	if err = putArrayLength(e, topicCount, flexible); err != nil {
 		return err
 	}
+	for _, topic := range r.Topics {
+		if err = putString(e, topic, flexible); err != nil {
+			return err
+		}
+		if err = putTaggedFields(e, flexible); err != nil {
+			return err
+		}
+	}
 	if r.APIVersion >= 4 {
 		e.PutBool(r.AllowAutoTopicCreation)
 	}
-	return nil
+	if r.APIVersion >= 8 {
+		e.PutBool(r.IncludeClusterAuthorizedOperations)
+		e.PutBool(r.IncludeTopicAuthorizedOperations)
+	}
+	return putTaggedFields(e, flexible)
 }
 
 func (r *MetadataRequest) Decode(d PacketDecoder, version int16) (err error) {
 	r.APIVersion = version
-	r.Topics, err = d.StringArray()
+	flexible := version >= 9
+	// Versions before 4 always allow auto topic creation
+	r.AllowAutoTopicCreation = true
+
+	// The topics array is nullable, which StringArray does not support
+	n, err := getNullableArrayLength(d, flexible)
 	if err != nil {
 		return err
 	}
+	if n >= 0 {
+		r.Topics = []string{}
+	}
+	for i := 0; i < n; i++ {
+		topic, err := getString(d, flexible)
+		if err != nil {
+			return err
+		}
+		if err = skipTaggedFields(d, flexible); err != nil {
+			return err
+		}
+		r.Topics = append(r.Topics, topic)
+	}
+	if version == 0 && len(r.Topics) == 0 {
+		r.Topics = nil
+	}
+
 	if version >= 4 {
-		r.AllowAutoTopicCreation, err = d.Bool()
+		if r.AllowAutoTopicCreation, err = d.Bool(); err != nil {
+			return err
+		}
+	}
+	if version >= 8 {
+		if r.IncludeClusterAuthorizedOperations, err = d.Bool(); err != nil {
+			return err
+		}
+		if r.IncludeTopicAuthorizedOperations, err = d.Bool(); err != nil {
+			return err
+		}
 	}
-	return err
+	return skipTaggedFields(d, flexible)
 }
 
 func (r *MetadataRequest) Key() int16 {
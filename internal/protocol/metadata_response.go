@@ -1,5 +1,10 @@
 package protocol
 
+import "time"
+
+// UnknownAuthorizedOperations is sent when authorized operations were not requested
+const UnknownAuthorizedOperations int32 = -2147483648
+
 type Broker struct {
 	NodeID int32
 	Host   string
@@ -11,155 +16,231 @@ type PartitionMetadata struct {
 	PartitionErrorCode int16
 	PartitionID        int32
 	Leader             int32
+	LeaderEpoch        int32
 	Replicas           []int32
 	ISR                []int32
+	OfflineReplicas    []int32
 }
 
 type TopicMetadata struct {
-	TopicErrorCode    int16
-	Topic             string
-	IsInternal        bool
-	PartitionMetadata []*PartitionMetadata
+	TopicErrorCode            int16
+	Topic                     string
+	IsInternal                bool
+	PartitionMetadata         []*PartitionMetadata
+	TopicAuthorizedOperations int32
 }
 
 type MetadataResponse struct {
 	APIVersion int16
 
-	Brokers       []*Broker
-	ControllerID  int32
-	TopicMetadata []*TopicMetadata
+	ThrottleTime                time.Duration
+	Brokers                     []*Broker
+	ClusterID                   *string
+	ControllerID                int32
+	TopicMetadata               []*TopicMetadata
+	ClusterAuthorizedOperations int32
 }
 
 func (r *MetadataResponse) Encode(e PacketEncoder) (err error) {
-	if err = e.PutArrayLength(len(r.Brokers)); err != nil {
+	flexible := r.APIVersion >= 9
+	if r.APIVersion >= 3 {
+		e.PutInt32(int32(r.ThrottleTime / time.Millisecond))
+	}
+	if err = putArrayLength(e, len(r.Brokers), flexible); err != nil {
 		return err
 	}
 	for _, b := range r.Brokers {
 		e.PutInt32(b.NodeID)
-		if err = e.PutString(b.Host); err != nil {
+		if err = putString(e, b.Host, flexible); err != nil {
 			return err
 		}
 		e.PutInt32(b.Port)
-		e.PutNullableString(b.Rack)
+		if r.APIVersion >= 1 {
+			if err = putNullableString(e, b.Rack, flexible); err != nil {
+				return err
+			}
+		}
+		if err = putTaggedFields(e, flexible); err != nil {
+			return err
+		}
+	}
+	if r.APIVersion >= 2 {
+		if err = putNullableString(e, r.ClusterID, flexible); err != nil {
+			return err
+		}
 	}
 	if r.APIVersion >= 1 {
 		e.PutInt32(r.ControllerID)
 	}
-	if err = e.PutArrayLength(len(r.TopicMetadata)); err != nil {
+	if err = putArrayLength(e, len(r.TopicMetadata), flexible); err != nil {
 		return err
 	}
 	for _, t := range r.TopicMetadata {
 		e.PutInt16(t.TopicErrorCode)
-		if err = e.PutString(t.Topic); err != nil {
+		if err = putString(e, t.Topic, flexible); err != nil {
 			return err
 		}
-		e.PutBool(t.IsInternal)
-		if err = e.PutArrayLength(len(t.PartitionMetadata)); err != nil {
+		if r.APIVersion >= 1 {
+			e.PutBool(t.IsInternal)
+		}
+		if err = putArrayLength(e, len(t.PartitionMetadata), flexible); err != nil {
 			return err
 		}
 		for _, p := range t.PartitionMetadata {
 			e.PutInt16(p.PartitionErrorCode)
 			e.PutInt32(p.PartitionID)
 			e.PutInt32(p.Leader)
-			if err = e.PutInt32Array(p.Replicas); err != nil {
+			if r.APIVersion >= 7 {
+				e.PutInt32(p.LeaderEpoch)
+			}
+			if err = putInt32Array(e, p.Replicas, flexible); err != nil {
+				return err
+			}
+			if err = putInt32Array(e, p.ISR, flexible); err != nil {
 				return err
 			}
-			if err = e.PutInt32Array(p.ISR); err != nil {
+			if r.APIVersion >= 5 {
+				if err = putInt32Array(e, p.OfflineReplicas, flexible); err != nil {
+					return err
+				}
+			}
+			if err = putTaggedFields(e, flexible); err != nil {
 				return err
 			}
 		}
+		if r.APIVersion >= 8 {
+			e.PutInt32(t.TopicAuthorizedOperations)
+		}
+		if err = putTaggedFields(e, flexible); err != nil {
+			return err
+		}
+	}
+	if r.APIVersion >= 8 {
+		e.PutInt32(r.ClusterAuthorizedOperations)
 	}
-	return nil
+	return putTaggedFields(e, flexible)
 }
 
 func (r *MetadataResponse) Decode(d PacketDecoder, version int16) (err error) {
 	r.APIVersion = version
+	flexible := version >= 9
 
-	brokerCount, err := d.ArrayLength()
+	if version >= 3 {
+		throttle, err := d.Int32()
+		if err != nil {
+			return err
+		}
+		r.ThrottleTime = time.Duration(throttle) * time.Millisecond
+	}
+	brokerCount, err := getArrayLength(d, flexible)
 	if err != nil {
 		return err
 	}
 	r.Brokers = make([]*Broker, brokerCount)
 	for i := range r.Brokers {
-		nodeID, err := d.Int32()
-		if err != nil {
+		b := &Broker{}
+		if b.NodeID, err = d.Int32(); err != nil {
 			return err
 		}
-		host, err := d.String()
-		if err != nil {
+		if b.Host, err = getString(d, flexible); err != nil {
 			return err
 		}
-		port, err := d.Int32()
-		if err != nil {
+		if b.Port, err = d.Int32(); err != nil {
 			return err
 		}
-
-		rack, err := d.NullableString()
-		if err != nil {
+		if version >= 1 {
+			if b.Rack, err = getNullableString(d, flexible); err != nil {
+				return err
+			}
+		}
+		if err = skipTaggedFields(d, flexible); err != nil {
 			return err
 		}
-		r.Brokers[i] = &Broker{
-			NodeID: nodeID,
-			Host:   host,
-			Port:   port,
-			Rack:   rack,
+		r.Brokers[i] = b
+	}
+	if version >= 2 {
+		if r.ClusterID, err = getNullableString(d, flexible); err != nil {
+			return err
 		}
 	}
+	r.ControllerID = -1
 	if version >= 1 {
-		r.ControllerID, err = d.Int32()
-		if err != nil {
+		if r.ControllerID, err = d.Int32(); err != nil {
 			return err
 		}
 	}
-	topicCount, err := d.ArrayLength()
+	topicCount, err := getArrayLength(d, flexible)
 	if err != nil {
 		return err
 	}
 	r.TopicMetadata = make([]*TopicMetadata, topicCount)
 	for i := range r.TopicMetadata {
-		m := &TopicMetadata{}
-		m.TopicErrorCode, err = d.Int16()
-		if err != nil {
+		m := &TopicMetadata{TopicAuthorizedOperations: UnknownAuthorizedOperations}
+		if m.TopicErrorCode, err = d.Int16(); err != nil {
 			return err
 		}
-		m.Topic, err = d.String()
-		if err != nil {
+		if m.Topic, err = getString(d, flexible); err != nil {
 			return err
 		}
-		m.IsInternal, err = d.Bool()
-		if err != nil {
-			return err
+		if version >= 1 {
+			if m.IsInternal, err = d.Bool(); err != nil {
+				return err
+			}
 		}
-		partitionCount, err := d.ArrayLength()
+		partitionCount, err := getArrayLength(d, flexible)
 		if err != nil {
 			return err
 		}
-		partitions := make([]*PartitionMetadata, partitionCount)
-		for i := range partitions {
-			p := &PartitionMetadata{}
-			p.PartitionErrorCode, err = d.Int16()
-			if err != nil {
+		m.PartitionMetadata = make([]*PartitionMetadata, partitionCount)
+		for j := range m.PartitionMetadata {
+			p := &PartitionMetadata{LeaderEpoch: -1}
+			if p.PartitionErrorCode, err = d.Int16(); err != nil {
 				return err
 			}
-			p.PartitionID, err = d.Int32()
-			if err != nil {
+			if p.PartitionID, err = d.Int32(); err != nil {
 				return err
 			}
-			p.Leader, err = d.Int32()
-			if err != nil {
+			if p.Leader, err = d.Int32(); err != nil {
 				return err
 			}
-			p.Replicas, err = d.Int32Array()
-			if err != nil {
+			if version >= 7 {
+				if p.LeaderEpoch, err = d.Int32(); err != nil {
+					return err
+				}
+			}
+			if p.Replicas, err = getInt32Array(d, flexible); err != nil {
 				return err
 			}
-			p.ISR, err = d.Int32Array()
-			partitions[i] = p
+			if p.ISR, err = getInt32Array(d, flexible); err != nil {
+				return err
+			}
+			if version >= 5 {
+				if p.OfflineReplicas, err = getInt32Array(d, flexible); err != nil {
+					return err
+				}
+			}
+			if err = skipTaggedFields(d, flexible); err != nil {
+				return err
+			}
+			m.PartitionMetadata[j] = p
+		}
+		if version >= 8 {
+			if m.TopicAuthorizedOperations, err = d.Int32(); err != nil {
+				return err
+			}
+		}
+		if err = skipTaggedFields(d, flexible); err != nil {
+			return err
 		}
-		m.PartitionMetadata = partitions
 		r.TopicMetadata[i] = m
 	}
-	return nil
+	r.ClusterAuthorizedOperations = UnknownAuthorizedOperations
+	if version >= 8 {
+		if r.ClusterAuthorizedOperations, err = d.Int32(); err != nil {
+			return err
+		}
+	}
+	return skipTaggedFields(d, flexible)
 }
 
 func (r *MetadataResponse) Version() int16 {
@@ -0,0 +1,149 @@
+package protocol
+
+type OffsetCommitPartition struct {
+	Partition            int32
+	CommittedOffset      int64
+	CommittedLeaderEpoch int32
+	// CommitTimestamp is only sent by version 1
+	CommitTimestamp   int64
+	CommittedMetadata *string
+}
+
+type OffsetCommitTopic struct {
+	Topic      string
+	Partitions []*OffsetCommitPartition
+}
+
+type OffsetCommitRequest struct {
+	APIVersion int16
+
+	GroupID         string
+	GenerationID    int32
+	MemberID        string
+	GroupInstanceID *string
+	// RetentionTime is sent by versions 2 to 4, -1 uses the retention of the broker
+	RetentionTime int64
+	Topics        []*OffsetCommitTopic
+}
+
+func (r *OffsetCommitRequest) Encode(e PacketEncoder) (err error) {
+	if err = e.PutString(r.GroupID); err != nil {
+		return err
+	}
+	if r.APIVersion >= 1 {
+		e.PutInt32(r.GenerationID)
+		if err = e.PutString(r.MemberID); err != nil {
+			return err
+		}
+	}
+	if r.APIVersion >= 7 {
+		if err = e.PutNullableString(r.GroupInstanceID); err != nil {
+			return err
+		}
+	}
+	if r.APIVersion >= 2 && r.APIVersion <= 4 {
+		e.PutInt64(r.RetentionTime)
+	}
+	if err = e.PutArrayLength(len(r.Topics)); err != nil {
+		return err
+	}
+	for _, t := range r.Topics {
+		if err = e.PutString(t.Topic); err != nil {
+			return err
+		}
+		if err = e.PutArrayLength(len(t.Partitions)); err != nil {
+			return err
+		}
+		for _, p := range t.Partitions {
+			e.PutInt32(p.Partition)
+			e.PutInt64(p.CommittedOffset)
+			if r.APIVersion >= 6 {
+				e.PutInt32(p.CommittedLeaderEpoch)
+			}
+			if r.APIVersion == 1 {
+				e.PutInt64(p.CommitTimestamp)
+			}
+			if err = e.PutNullableString(p.CommittedMetadata); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *OffsetCommitRequest) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	r.GenerationID = -1
+	r.RetentionTime = -1
+
+	if r.GroupID, err = d.String(); err != nil {
+		return err
+	}
+	if version >= 1 {
+		if r.GenerationID, err = d.Int32(); err != nil {
+			return err
+		}
+		if r.MemberID, err = d.String(); err != nil {
+			return err
+		}
+	}
+	if version >= 7 {
+		if r.GroupInstanceID, err = d.NullableString(); err != nil {
+			return err
+		}
+	}
+	if version >= 2 && version <= 4 {
+		if r.RetentionTime, err = d.Int64(); err != nil {
+			return err
+		}
+	}
+	n, err := d.ArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Topics = make([]*OffsetCommitTopic, n)
+	for i := range r.Topics {
+		t := &OffsetCommitTopic{}
+		if t.Topic, err = d.String(); err != nil {
+			return err
+		}
+		pn, err := d.ArrayLength()
+		if err != nil {
+			return err
+		}
+		t.Partitions = make([]*OffsetCommitPartition, pn)
+		for j := range t.Partitions {
+			p := &OffsetCommitPartition{CommittedLeaderEpoch: -1, CommitTimestamp: -1}
+			if p.Partition, err = d.Int32(); err != nil {
+				return err
+			}
+			if p.CommittedOffset, err = d.Int64(); err != nil {
+				return err
+			}
+			if version >= 6 {
+				if p.CommittedLeaderEpoch, err = d.Int32(); err != nil {
+					return err
+				}
+			}
+			if version == 1 {
+				if p.CommitTimestamp, err = d.Int64(); err != nil {
+					return err
+				}
+			}
+			if p.CommittedMetadata, err = d.NullableString(); err != nil {
+				return err
+			}
+			t.Partitions[j] = p
+		}
+		r.Topics[i] = t
+	}
+	return nil
+}
+
+func (r *OffsetCommitRequest) Key() int16 {
+	return OffsetCommitKey
+}
+
+func (r *OffsetCommitRequest) Version() int16 {
+	return r.APIVersion
+}
@@ -0,0 +1,85 @@
+package protocol
+
+import "time"
+
+type OffsetCommitPartitionResponse struct {
+	Partition int32
+	ErrorCode int16
+}
+
+type OffsetCommitTopicResponse struct {
+	Topic      string
+	Partitions []*OffsetCommitPartitionResponse
+}
+
+type OffsetCommitResponse struct {
+	APIVersion int16
+
+	ThrottleTime time.Duration
+	Topics       []*OffsetCommitTopicResponse
+}
+
+func (r *OffsetCommitResponse) Encode(e PacketEncoder) (err error) {
+	if r.APIVersion >= 3 {
+		e.PutInt32(int32(r.ThrottleTime / time.Millisecond))
+	}
+	if err = e.PutArrayLength(len(r.Topics)); err != nil {
+		return err
+	}
+	for _, t := range r.Topics {
+		if err = e.PutString(t.Topic); err != nil {
+			return err
+		}
+		if err = e.PutArrayLength(len(t.Partitions)); err != nil {
+			return err
+		}
+		for _, p := range t.Partitions {
+			e.PutInt32(p.Partition)
+			e.PutInt16(p.ErrorCode)
+		}
+	}
+	return nil
+}
+
+func (r *OffsetCommitResponse) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	if version >= 3 {
+		throttle, err := d.Int32()
+		if err != nil {
+			return err
+		}
+		r.ThrottleTime = time.Duration(throttle) * time.Millisecond
+	}
+	n, err := d.ArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Topics = make([]*OffsetCommitTopicResponse, n)
+	for i := range r.Topics {
+		t := &OffsetCommitTopicResponse{}
+		if t.Topic, err = d.String(); err != nil {
+			return err
+		}
+		pn, err := d.ArrayLength()
+		if err != nil {
+			return err
+		}
+		t.Partitions = make([]*OffsetCommitPartitionResponse, pn)
+		for j := range t.Partitions {
+			p := &OffsetCommitPartitionResponse{}
+			if p.Partition, err = d.Int32(); err != nil {
+				return err
+			}
+			if p.ErrorCode, err = d.Int16(); err != nil {
+				return err
+			}
+			t.Partitions[j] = p
+		}
+		r.Topics[i] = t
+	}
+	return nil
+}
+
+func (r *OffsetCommitResponse) Version() int16 {
+	return r.APIVersion
+}
@@ -0,0 +1,71 @@
+package protocol
+
+type OffsetFetchTopic struct {
+	Topic      string
+	Partitions []int32
+}
+
+type OffsetFetchRequest struct {
+	APIVersion int16
+
+	GroupID string
+	// Topics is null from version 2 on to fetch all offsets of the group
+	Topics []*OffsetFetchTopic
+}
+
+func (r *OffsetFetchRequest) Encode(e PacketEncoder) (err error) {
+	if err = e.PutString(r.GroupID); err != nil {
+		return err
+	}
+	if r.Topics == nil && r.APIVersion >= 2 {
+		e.PutInt32(-1)
+		return nil
+	}
+	if err = e.PutArrayLength(len(r.Topics)); err != nil {
+		return err
+	}
+	for _, t := range r.Topics {
+		if err = e.PutString(t.Topic); err != nil {
+			return err
+		}
+		if err = e.PutInt32Array(t.Partitions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *OffsetFetchRequest) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	if r.GroupID, err = d.String(); err != nil {
+		return err
+	}
+	// The topics array is nullable, which ArrayLength does not support
+	n, err := d.Int32()
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		return nil
+	}
+	r.Topics = []*OffsetFetchTopic{}
+	for i := int32(0); i < n; i++ {
+		t := &OffsetFetchTopic{}
+		if t.Topic, err = d.String(); err != nil {
+			return err
+		}
+		if t.Partitions, err = d.Int32Array(); err != nil {
+			return err
+		}
+		r.Topics = append(r.Topics, t)
+	}
+	return nil
+}
+
+func (r *OffsetFetchRequest) Key() int16 {
+	return OffsetFetchKey
+}
+
+func (r *OffsetFetchRequest) Version() int16 {
+	return r.APIVersion
+}
@@ -0,0 +1,113 @@
+package protocol
+
+import "time"
+
+type OffsetFetchPartitionResponse struct {
+	Partition            int32
+	CommittedOffset      int64
+	CommittedLeaderEpoch int32
+	Metadata             *string
+	ErrorCode            int16
+}
+
+type OffsetFetchTopicResponse struct {
+	Topic      string
+	Partitions []*OffsetFetchPartitionResponse
+}
+
+type OffsetFetchResponse struct {
+	APIVersion int16
+
+	ThrottleTime time.Duration
+	Topics       []*OffsetFetchTopicResponse
+	ErrorCode    int16
+}
+
+func (r *OffsetFetchResponse) Encode(e PacketEncoder) (err error) {
+	if r.APIVersion >= 3 {
+		e.PutInt32(int32(r.ThrottleTime / time.Millisecond))
+	}
+	if err = e.PutArrayLength(len(r.Topics)); err != nil {
+		return err
+	}
+	for _, t := range r.Topics {
+		if err = e.PutString(t.Topic); err != nil {
+			return err
+		}
+		if err = e.PutArrayLength(len(t.Partitions)); err != nil {
+			return err
+		}
+		for _, p := range t.Partitions {
+			e.PutInt32(p.Partition)
+			e.PutInt64(p.CommittedOffset)
+			if r.APIVersion >= 5 {
+				e.PutInt32(p.CommittedLeaderEpoch)
+			}
+			if err = e.PutNullableString(p.Metadata); err != nil {
+				return err
+			}
+			e.PutInt16(p.ErrorCode)
+		}
+	}
+	if r.APIVersion >= 2 {
+		e.PutInt16(r.ErrorCode)
+	}
+	return nil
+}
+
+func (r *OffsetFetchResponse) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	if version >= 3 {
+		throttle, err := d.Int32()
+		if err != nil {
+			return err
+		}
+		r.ThrottleTime = time.Duration(throttle) * time.Millisecond
+	}
+	n, err := d.ArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Topics = make([]*OffsetFetchTopicResponse, n)
+	for i := range r.Topics {
+		t := &OffsetFetchTopicResponse{}
+		if t.Topic, err = d.String(); err != nil {
+			return err
+		}
+		pn, err := d.ArrayLength()
+		if err != nil {
+			return err
+		}
+		t.Partitions = make([]*OffsetFetchPartitionResponse, pn)
+		for j := range t.Partitions {
+			p := &OffsetFetchPartitionResponse{CommittedLeaderEpoch: -1}
+			if p.Partition, err = d.Int32(); err != nil {
+				return err
+			}
+			if p.CommittedOffset, err = d.Int64(); err != nil {
+				return err
+			}
+			if version >= 5 {
+				if p.CommittedLeaderEpoch, err = d.Int32(); err != nil {
+					return err
+				}
+			}
+			if p.Metadata, err = d.NullableString(); err != nil {
+				return err
+			}
+			if p.ErrorCode, err = d.Int16(); err != nil {
+				return err
+			}
+			t.Partitions[j] = p
+		}
+		r.Topics[i] = t
+	}
+	if version >= 2 {
+		r.ErrorCode, err = d.Int16()
+	}
+	return err
+}
+
+func (r *OffsetFetchResponse) Version() int16 {
+	return r.APIVersion
+}
@@ -10,9 +10,10 @@ type ProduceRequest struct {
 
 func (r *ProduceRequest) Decode(d PacketDecoder, version int16) error {
 	r.Version = version
+	flexible := version >= 9
 
 	if version >= 3 {
-		id, err := d.NullableString()
+		id, err := getNullableString(d, flexible)
 		if err != nil {
 			return err
 		}
@@ -26,21 +27,21 @@ func (r *ProduceRequest) Decode(d PacketDecoder, version int16) error {
 	if r.Timeout, err = d.Int32(); err != nil {
 		return err
 	}
-	topicCount, err := d.ArrayLength()
+	topicCount, err := getArrayLength(d, flexible)
 	if err != nil {
 		return err
 	}
 	if topicCount == 0 {
-		return nil
+		return skipTaggedFields(d, flexible)
 	}
 
 	r.Records = make(map[string]map[int32]Records)
 	for i := 0; i < topicCount; i++ {
-		topic, err := d.String()
+		topic, err := getString(d, flexible)
 		if err != nil {
 			return err
 		}
-		partitionCount, err := d.ArrayLength()
+		partitionCount, err := getArrayLength(d, flexible)
 		if err != nil {
 			return err
 		}
@@ -51,21 +52,23 @@ func (r *ProduceRequest) Decode(d PacketDecoder, version int16) error {
 			if err != nil {
 				return err
 			}
-			size, err := d.Int32()
+			buf, err := getBytes(d, flexible)
 			if err != nil {
 				return err
 			}
-			recordsDecoder, err := d.Subset(int(size))
-			if err != nil {
+			var records Records
+			if err := records.Decode(NewDecoder(buf)); err != nil {
 				return err
 			}
-			var records Records
-			if err := records.Decode(recordsDecoder); err != nil {
+			if err := skipTaggedFields(d, flexible); err != nil {
 				return err
 			}
 			r.Records[topic][partition] = records
 		}
+		if err := skipTaggedFields(d, flexible); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return skipTaggedFields(d, flexible)
 }
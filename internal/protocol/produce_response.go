@@ -2,12 +2,20 @@ package protocol
 
 import "time"
 
+// RecordError is a record of a batch which made the whole batch fail
+type RecordError struct {
+	BatchIndex   int32
+	ErrorMessage *string
+}
+
 type ProducePartitionResponse struct {
 	Partition      int32
 	ErrorCode      int16
 	BaseOffset     int64
 	LogAppendTime  time.Time
 	LogStartOffset int64
+	RecordErrors   []*RecordError
+	ErrorMessage   *string
 }
 
 type ProduceTopicResponse struct {
@@ -23,32 +31,61 @@ type ProduceResponse struct {
 }
 
 func (r *ProduceResponse) Encode(e PacketEncoder) (err error) {
-	if err = e.PutArrayLength(len(r.Responses)); err != nil {
+	flexible := r.APIVersion >= 9
+	if err = putArrayLength(e, len(r.Responses), flexible); err != nil {
 		return err
 	}
 	for _, resp := range r.Responses {
-		if err = e.PutString(resp.Topic); err != nil {
+		if err = putString(e, resp.Topic, flexible); err != nil {
 			return err
 		}
-		if err = e.PutArrayLength(len(resp.PartitionResponses)); err != nil {
+		if err = putArrayLength(e, len(resp.PartitionResponses), flexible); err != nil {
 			return err
 		}
 		for _, p := range resp.PartitionResponses {
 			e.PutInt32(p.Partition)
 			e.PutInt16(p.ErrorCode)
+			e.PutInt64(p.BaseOffset)
 			if r.APIVersion >= 2 {
-				e.PutInt64(p.BaseOffset)
-				e.PutInt64(int64(p.LogAppendTime.UnixNano() / int64(time.Millisecond)))
+				// -1 signals that the broker kept the producer's CreateTime timestamps
+				logAppendTime := int64(-1)
+				if !p.LogAppendTime.IsZero() {
+					logAppendTime = p.LogAppendTime.UnixNano() / int64(time.Millisecond)
+				}
+				e.PutInt64(logAppendTime)
 			}
 			if r.APIVersion >= 5 {
 				e.PutInt64(p.LogStartOffset)
 			}
+			if r.APIVersion >= 8 {
+				if err = putArrayLength(e, len(p.RecordErrors), flexible); err != nil {
+					return err
+				}
+				for _, re := range p.RecordErrors {
+					e.PutInt32(re.BatchIndex)
+					if err = putNullableString(e, re.ErrorMessage, flexible); err != nil {
+						return err
+					}
+					if err = putTaggedFields(e, flexible); err != nil {
+						return err
+					}
+				}
+				if err = putNullableString(e, p.ErrorMessage, flexible); err != nil {
+					return err
+				}
+			}
+			if err = putTaggedFields(e, flexible); err != nil {
+				return err
+			}
+		}
+		if err = putTaggedFields(e, flexible); err != nil {
+			return err
 		}
 	}
 	if r.APIVersion >= 1 {
 		e.PutInt32(int32(r.ThrottleTime / time.Millisecond))
 	}
-	return nil
+	return putTaggedFields(e, flexible)
 }
 
 type ProduceTopicResponses []*ProduceTopicResponse
@@ -57,7 +94,8 @@ type ProducePartitionResponses []*ProducePartitionResponse
 
 func (r *ProduceResponse) Decode(d PacketDecoder, version int16) (err error) {
 	r.APIVersion = version
-	l, err := d.ArrayLength()
+	flexible := version >= 9
+	l, err := getArrayLength(d, flexible)
 	if err != nil {
 		return err
 	}
@@ -65,11 +103,11 @@ func (r *ProduceResponse) Decode(d PacketDecoder, version int16) (err error) {
 	for i := range r.Responses {
 		resp := new(ProduceTopicResponse)
 		r.Responses[i] = resp
-		resp.Topic, err = d.String()
+		resp.Topic, err = getString(d, flexible)
 		if err != nil {
 			return err
 		}
-		pl, err := d.ArrayLength()
+		pl, err := getArrayLength(d, flexible)
 		if err != nil {
 			return err
 		}
@@ -95,7 +133,9 @@ func (r *ProduceResponse) Decode(d PacketDecoder, version int16) (err error) {
 				if err != nil {
 					return err
 				}
-				p.LogAppendTime = time.Unix(millis/1000, (millis%1000)*int64(time.Millisecond))
+				if millis >= 0 {
+					p.LogAppendTime = time.Unix(millis/1000, (millis%1000)*int64(time.Millisecond))
+				}
 			}
 			if r.APIVersion >= 5 {
 				p.LogStartOffset, err = d.Int64()
@@ -103,6 +143,34 @@ func (r *ProduceResponse) Decode(d PacketDecoder, version int16) (err error) {
 					return err
 				}
 			}
+			if r.APIVersion >= 8 {
+				n, err := getArrayLength(d, flexible)
+				if err != nil {
+					return err
+				}
+				for k := 0; k < n; k++ {
+					re := new(RecordError)
+					if re.BatchIndex, err = d.Int32(); err != nil {
+						return err
+					}
+					if re.ErrorMessage, err = getNullableString(d, flexible); err != nil {
+						return err
+					}
+					if err = skipTaggedFields(d, flexible); err != nil {
+						return err
+					}
+					p.RecordErrors = append(p.RecordErrors, re)
+				}
+				if p.ErrorMessage, err = getNullableString(d, flexible); err != nil {
+					return err
+				}
+			}
+			if err = skipTaggedFields(d, flexible); err != nil {
+				return err
+			}
+		}
+		if err = skipTaggedFields(d, flexible); err != nil {
+			return err
 		}
 		resp.PartitionResponses = ps
 	}
@@ -113,6 +181,5 @@ func (r *ProduceResponse) Decode(d PacketDecoder, version int16) (err error) {
 		}
 		r.ThrottleTime = time.Duration(throttle) * time.Millisecond
 	}
-	return nil
-
+	return skipTaggedFields(d, flexible)
 }
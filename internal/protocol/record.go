@@ -75,10 +75,6 @@ func (r *Record) Decode(pd PacketDecoder) (err error) {
 		return err
 	}
 
-	// Hack fix to get proper decoding going
-	pd.Int8()
-	// Hack fix to get proper decoding going
-
 	if r.Attributes, err = pd.Int8(); err != nil {
 		return err
 	}
@@ -117,6 +113,5 @@ func (r *Record) Decode(pd PacketDecoder) (err error) {
 		r.Headers[i] = hdr
 	}
 
-	pd.Pop()
-	return nil
+	return pd.Pop()
 }
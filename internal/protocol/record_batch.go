@@ -189,7 +189,7 @@ func (b *RecordBatch) Decode(pd PacketDecoder) (err error) {
 		b.Records = nil
 		return nil
 	}
-	return nil
+	return err
 }
 
 func (b *RecordBatch) EncodeRecords(pe PacketEncoder) error {
@@ -20,6 +20,11 @@ func (r *Request) Encode(pe PacketEncoder) (err error) {
 	if err = pe.PutString(r.ClientID); err != nil {
 		return err
 	}
+	if Flexible(r.Body.Key(), r.Body.Version()) {
+		if err = pe.PutTaggedFields(nil); err != nil {
+			return err
+		}
+	}
 	if err = r.Body.Encode(pe); err != nil {
 		return err
 	}
@@ -24,6 +24,10 @@ func (r *RequestHeader) Encode(e PacketEncoder) {
 		// TODO: better err handling
 		panic(err)
 	}
+	// Header version 2 of the flexible versions adds tagged fields
+	if r.Flexible() {
+		e.PutTaggedFields(nil)
+	}
 }
 
 func (r *RequestHeader) Decode(d PacketDecoder) error {
@@ -44,10 +48,31 @@ func (r *RequestHeader) Decode(d PacketDecoder) error {
 	if err != nil {
 		return err
 	}
-	r.ClientID, err = d.String()
+	if r.ClientID, err = d.String(); err != nil {
+		return err
+	}
+	// Header version 2 of the flexible versions adds tagged fields
+	if r.Flexible() {
+		_, err = d.TaggedFields()
+	}
 	return err
 }
 
+// Flexible reports whether the request uses the flexible encoding
+func (r *RequestHeader) Flexible() bool {
+	return Flexible(r.APIKey, r.APIVersion)
+}
+
+// ResponseHeaderVersion returns the header version of the response to the request.
+// ApiVersions responses always use version 0, so that clients can read them whatever
+// version they asked for.
+func (r *RequestHeader) ResponseHeaderVersion() int16 {
+	if r.Flexible() && r.APIKey != APIVersionsKey {
+		return 1
+	}
+	return 0
+}
+
 func (r *RequestHeader) String() string {
 	return fmt.Sprintf(
 		"correlation id: %d, api key: %d, client: %s, size: %d",
@@ -8,14 +8,18 @@ type ResponseBody interface {
 type Response struct {
 	Size          int32
 	CorrelationID int32
+	// HeaderVersion 1 adds the tagged fields of the flexible versions
+	HeaderVersion int16
 	Body          ResponseBody
 }
 
 func (r Response) Encode(pe PacketEncoder) (err error) {
 	pe.Push(&SizeField{})
 	pe.PutInt32(r.CorrelationID)
-	if err != nil {
-		return err
+	if r.HeaderVersion >= 1 {
+		if err = pe.PutTaggedFields(nil); err != nil {
+			return err
+		}
 	}
 	err = r.Body.Encode(pe)
 	if err != nil {
@@ -33,8 +37,25 @@ func (r Response) Decode(pd PacketDecoder, version int16) (err error) {
 	if r.CorrelationID, err = pd.Int32(); err != nil {
 		return err
 	}
+	if r.HeaderVersion >= 1 {
+		if _, err = pd.TaggedFields(); err != nil {
+			return err
+		}
+	}
 	if r.Body != nil {
 		return r.Body.Decode(pd, version)
 	}
 	return nil
 }
+
+// RawBody is a response body which is already encoded
+type RawBody []byte
+
+func (r RawBody) Encode(pe PacketEncoder) error {
+	return pe.PutRawBytes(r)
+}
+
+func (r *RawBody) Decode(pd PacketDecoder, version int16) (err error) {
+	*r, err = pd.RawBytes(pd.remaining())
+	return err
+}
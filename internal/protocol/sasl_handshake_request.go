@@ -0,0 +1,25 @@
+package protocol
+
+type SaslHandshakeRequest struct {
+	APIVersion int16
+
+	Mechanism string
+}
+
+func (r *SaslHandshakeRequest) Encode(e PacketEncoder) error {
+	return e.PutString(r.Mechanism)
+}
+
+func (r *SaslHandshakeRequest) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	r.Mechanism, err = d.String()
+	return err
+}
+
+func (r *SaslHandshakeRequest) Key() int16 {
+	return SaslHandshakeKey
+}
+
+func (r *SaslHandshakeRequest) Version() int16 {
+	return r.APIVersion
+}
@@ -0,0 +1,26 @@
+package protocol
+
+type SaslHandshakeResponse struct {
+	APIVersion int16
+
+	ErrorCode  int16
+	Mechanisms []string
+}
+
+func (r *SaslHandshakeResponse) Encode(e PacketEncoder) error {
+	e.PutInt16(r.ErrorCode)
+	return e.PutStringArray(r.Mechanisms)
+}
+
+func (r *SaslHandshakeResponse) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	if r.ErrorCode, err = d.Int16(); err != nil {
+		return err
+	}
+	r.Mechanisms, err = d.StringArray()
+	return err
+}
+
+func (r *SaslHandshakeResponse) Version() int16 {
+	return r.APIVersion
+}
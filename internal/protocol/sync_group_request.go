@@ -0,0 +1,85 @@
+package protocol
+
+type SyncGroupAssignment struct {
+	MemberID   string
+	Assignment []byte
+}
+
+type SyncGroupRequest struct {
+	APIVersion int16
+
+	GroupID         string
+	GenerationID    int32
+	MemberID        string
+	GroupInstanceID *string
+	Assignments     []*SyncGroupAssignment
+}
+
+func (r *SyncGroupRequest) Encode(e PacketEncoder) (err error) {
+	if err = e.PutString(r.GroupID); err != nil {
+		return err
+	}
+	e.PutInt32(r.GenerationID)
+	if err = e.PutString(r.MemberID); err != nil {
+		return err
+	}
+	if r.APIVersion >= 3 {
+		if err = e.PutNullableString(r.GroupInstanceID); err != nil {
+			return err
+		}
+	}
+	if err = e.PutArrayLength(len(r.Assignments)); err != nil {
+		return err
+	}
+	for _, a := range r.Assignments {
+		if err = e.PutString(a.MemberID); err != nil {
+			return err
+		}
+		if err = e.PutBytes(a.Assignment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SyncGroupRequest) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	if r.GroupID, err = d.String(); err != nil {
+		return err
+	}
+	if r.GenerationID, err = d.Int32(); err != nil {
+		return err
+	}
+	if r.MemberID, err = d.String(); err != nil {
+		return err
+	}
+	if version >= 3 {
+		if r.GroupInstanceID, err = d.NullableString(); err != nil {
+			return err
+		}
+	}
+	n, err := d.ArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Assignments = make([]*SyncGroupAssignment, n)
+	for i := range r.Assignments {
+		a := &SyncGroupAssignment{}
+		if a.MemberID, err = d.String(); err != nil {
+			return err
+		}
+		if a.Assignment, err = d.Bytes(); err != nil {
+			return err
+		}
+		r.Assignments[i] = a
+	}
+	return nil
+}
+
+func (r *SyncGroupRequest) Key() int16 {
+	return SyncGroupKey
+}
+
+func (r *SyncGroupRequest) Version() int16 {
+	return r.APIVersion
+}
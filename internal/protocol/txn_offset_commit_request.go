@@ -0,0 +1,116 @@
+package protocol
+
+type TxnOffsetCommitPartition struct {
+	Partition            int32
+	CommittedOffset      int64
+	CommittedLeaderEpoch int32
+	CommittedMetadata    *string
+}
+
+type TxnOffsetCommitTopic struct {
+	Name       string
+	Partitions []*TxnOffsetCommitPartition
+}
+
+type TxnOffsetCommitRequest struct {
+	APIVersion int16
+
+	TransactionalID string
+	GroupID         string
+	ProducerID      int64
+	ProducerEpoch   int16
+	Topics          []*TxnOffsetCommitTopic
+}
+
+func (r *TxnOffsetCommitRequest) Encode(e PacketEncoder) (err error) {
+	if err = e.PutString(r.TransactionalID); err != nil {
+		return err
+	}
+	if err = e.PutString(r.GroupID); err != nil {
+		return err
+	}
+	e.PutInt64(r.ProducerID)
+	e.PutInt16(r.ProducerEpoch)
+	if err = e.PutArrayLength(len(r.Topics)); err != nil {
+		return err
+	}
+	for _, t := range r.Topics {
+		if err = e.PutString(t.Name); err != nil {
+			return err
+		}
+		if err = e.PutArrayLength(len(t.Partitions)); err != nil {
+			return err
+		}
+		for _, p := range t.Partitions {
+			e.PutInt32(p.Partition)
+			e.PutInt64(p.CommittedOffset)
+			if r.APIVersion >= 2 {
+				e.PutInt32(p.CommittedLeaderEpoch)
+			}
+			if err = e.PutNullableString(p.CommittedMetadata); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *TxnOffsetCommitRequest) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	if r.TransactionalID, err = d.String(); err != nil {
+		return err
+	}
+	if r.GroupID, err = d.String(); err != nil {
+		return err
+	}
+	if r.ProducerID, err = d.Int64(); err != nil {
+		return err
+	}
+	if r.ProducerEpoch, err = d.Int16(); err != nil {
+		return err
+	}
+	n, err := d.ArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Topics = make([]*TxnOffsetCommitTopic, n)
+	for i := range r.Topics {
+		t := &TxnOffsetCommitTopic{}
+		if t.Name, err = d.String(); err != nil {
+			return err
+		}
+		pn, err := d.ArrayLength()
+		if err != nil {
+			return err
+		}
+		t.Partitions = make([]*TxnOffsetCommitPartition, pn)
+		for j := range t.Partitions {
+			p := &TxnOffsetCommitPartition{CommittedLeaderEpoch: -1}
+			if p.Partition, err = d.Int32(); err != nil {
+				return err
+			}
+			if p.CommittedOffset, err = d.Int64(); err != nil {
+				return err
+			}
+			if version >= 2 {
+				if p.CommittedLeaderEpoch, err = d.Int32(); err != nil {
+					return err
+				}
+			}
+			if p.CommittedMetadata, err = d.NullableString(); err != nil {
+				return err
+			}
+			t.Partitions[j] = p
+		}
+		r.Topics[i] = t
+	}
+	return nil
+}
+
+func (r *TxnOffsetCommitRequest) Key() int16 {
+	return TxnOffsetCommitKey
+}
+
+func (r *TxnOffsetCommitRequest) Version() int16 {
+	return r.APIVersion
+}
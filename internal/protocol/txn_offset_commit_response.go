@@ -0,0 +1,81 @@
+package protocol
+
+import "time"
+
+type TxnOffsetCommitPartitionResponse struct {
+	Partition int32
+	ErrorCode int16
+}
+
+type TxnOffsetCommitTopicResponse struct {
+	Name       string
+	Partitions []*TxnOffsetCommitPartitionResponse
+}
+
+type TxnOffsetCommitResponse struct {
+	APIVersion int16
+
+	ThrottleTime time.Duration
+	Topics       []*TxnOffsetCommitTopicResponse
+}
+
+func (r *TxnOffsetCommitResponse) Encode(e PacketEncoder) (err error) {
+	e.PutInt32(int32(r.ThrottleTime / time.Millisecond))
+	if err = e.PutArrayLength(len(r.Topics)); err != nil {
+		return err
+	}
+	for _, t := range r.Topics {
+		if err = e.PutString(t.Name); err != nil {
+			return err
+		}
+		if err = e.PutArrayLength(len(t.Partitions)); err != nil {
+			return err
+		}
+		for _, p := range t.Partitions {
+			e.PutInt32(p.Partition)
+			e.PutInt16(p.ErrorCode)
+		}
+	}
+	return nil
+}
+
+func (r *TxnOffsetCommitResponse) Decode(d PacketDecoder, version int16) (err error) {
+	r.APIVersion = version
+	throttle, err := d.Int32()
+	if err != nil {
+		return err
+	}
+	r.ThrottleTime = time.Duration(throttle) * time.Millisecond
+	n, err := d.ArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Topics = make([]*TxnOffsetCommitTopicResponse, n)
+	for i := range r.Topics {
+		t := &TxnOffsetCommitTopicResponse{}
+		if t.Name, err = d.String(); err != nil {
+			return err
+		}
+		pn, err := d.ArrayLength()
+		if err != nil {
+			return err
+		}
+		t.Partitions = make([]*TxnOffsetCommitPartitionResponse, pn)
+		for j := range t.Partitions {
+			p := &TxnOffsetCommitPartitionResponse{}
+			if p.Partition, err = d.Int32(); err != nil {
+				return err
+			}
+			if p.ErrorCode, err = d.Int16(); err != nil {
+				return err
+			}
+			t.Partitions[j] = p
+		}
+		r.Topics[i] = t
+	}
+	return nil
+}
+
+func (r *TxnOffsetCommitResponse) Version() int16 {
+	return r.APIVersion
+}
@@ -0,0 +1,34 @@
+package sasl
+
+import (
+	"bytes"
+	"crypto/subtle"
+)
+
+// plain implements PLAIN, where the client sends its password in a single token
+type plain struct {
+	users    map[string]string
+	username string
+}
+
+// Next checks the authzid, username and password separated by NUL bytes
+func (p *plain) Next(token []byte) ([]byte, bool, error) {
+	parts := bytes.Split(token, []byte{0})
+	if len(parts) != 3 {
+		return nil, false, AuthenticationError{Mechanism: Plain, Reason: "an invalid PLAIN token"}
+	}
+	authzid, username, password := string(parts[0]), string(parts[1]), parts[2]
+	if authzid != "" && authzid != username {
+		return nil, false, AuthenticationError{Mechanism: Plain, Reason: "an authorization ID different from the user name"}
+	}
+	expected, ok := p.users[username]
+	if !ok || subtle.ConstantTimeCompare([]byte(expected), password) != 1 {
+		return nil, false, AuthenticationError{Mechanism: Plain, Reason: invalidCredentials}
+	}
+	p.username = username
+	return []byte{}, true, nil
+}
+
+func (p *plain) Username() string {
+	return p.username
+}
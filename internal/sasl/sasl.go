@@ -0,0 +1,71 @@
+// Server side of the SASL mechanisms supported by Kafka
+package sasl
+
+import (
+	"fmt"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+)
+
+// Names of the supported mechanisms
+const (
+	Plain       = "PLAIN"
+	ScramSHA256 = "SCRAM-SHA-256"
+	ScramSHA512 = "SCRAM-SHA-512"
+)
+
+// Mechanisms are the supported mechanisms
+var Mechanisms = []string{Plain, ScramSHA256, ScramSHA512}
+
+// Supported tells whether a mechanism is supported
+func Supported(mechanism string) bool {
+	for _, m := range Mechanisms {
+		if m == mechanism {
+			return true
+		}
+	}
+	return false
+}
+
+// Mechanism authenticates a single client by exchanging SASL tokens with it
+type Mechanism interface {
+	// Next handles a token of the client and returns the token to send back. Done is
+	// set once the client is authenticated.
+	Next(token []byte) (reply []byte, done bool, err error)
+	// Username returns the name of the authenticated user
+	Username() string
+}
+
+// New starts the authentication of a client with the given mechanism. Users maps
+// the names of the users to their passwords.
+func New(mechanism string, users map[string]string) (Mechanism, error) {
+	switch mechanism {
+	case Plain:
+		return &plain{users: users}, nil
+	case ScramSHA256:
+		return newScram(mechanism, sha256Hash, users), nil
+	case ScramSHA512:
+		return newScram(mechanism, sha512Hash, users), nil
+	default:
+		return nil, protocol.ErrUnsupportedSaslMechanism
+	}
+}
+
+// AuthenticationError is returned when a client fails to authenticate, it wraps
+// ErrSaslAuthenticationFailed
+type AuthenticationError struct {
+	Mechanism string
+	Reason    string
+}
+
+func (e AuthenticationError) Error() string {
+	return fmt.Sprintf("Authentication failed during authentication due to %s with SASL mechanism %s", e.Reason, e.Mechanism)
+}
+
+func (e AuthenticationError) Unwrap() error {
+	return protocol.ErrSaslAuthenticationFailed
+}
+
+// invalidCredentials is the reason given for unknown users and wrong passwords, like
+// Kafka it does not tell them apart
+const invalidCredentials = "invalid credentials"
@@ -0,0 +1,180 @@
+package sasl
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+// scramIterations is the iteration count of the salted passwords, the minimum of Kafka
+const scramIterations = 4096
+
+func sha256Hash() hash.Hash { return sha256.New() }
+func sha512Hash() hash.Hash { return sha512.New() }
+
+// scram implements SCRAM-SHA-256 and SCRAM-SHA-512 of RFC 5802 without channel
+// binding. The credentials are salted from the configured passwords for every client.
+type scram struct {
+	name  string
+	hash  func() hash.Hash
+	users map[string]string
+
+	username        string
+	nonce           string
+	gs2Header       string
+	clientFirstBare string
+	serverFirst     string
+	saltedPassword  []byte
+	done            bool
+}
+
+func newScram(name string, h func() hash.Hash, users map[string]string) *scram {
+	return &scram{name: name, hash: h, users: users}
+}
+
+// Next answers the client first message with the salt and the iteration count, and
+// the client final message with the server signature once the proof is verified
+func (s *scram) Next(token []byte) ([]byte, bool, error) {
+	switch {
+	case s.done:
+		return nil, false, s.fail("an unexpected SCRAM message")
+	case s.serverFirst == "":
+		return s.first(string(token))
+	default:
+		return s.final(string(token))
+	}
+}
+
+func (s *scram) Username() string {
+	return s.username
+}
+
+func (s *scram) first(msg string) ([]byte, bool, error) {
+	// gs2-cbind-flag "," [ authzid ] "," client-first-message-bare
+	parts := strings.SplitN(msg, ",", 3)
+	if len(parts) != 3 || (parts[0] != "n" && parts[0] != "y") {
+		return nil, false, s.fail("an invalid SCRAM client first message")
+	}
+	s.gs2Header = parts[0] + "," + parts[1] + ","
+	s.clientFirstBare = parts[2]
+
+	attrs := scramAttributes(s.clientFirstBare)
+	username := scramUnescape(attrs["n"])
+	if attrs["r"] == "" || username == "" {
+		return nil, false, s.fail("an invalid SCRAM client first message")
+	}
+	if authzid := strings.TrimPrefix(parts[1], "a="); authzid != "" && scramUnescape(authzid) != username {
+		return nil, false, s.fail("an authorization ID different from the user name")
+	}
+	password, ok := s.users[username]
+	if !ok {
+		return nil, false, s.fail(invalidCredentials)
+	}
+	s.username = username
+
+	salt := make([]byte, 16)
+	nonce := make([]byte, 18)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, false, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, false, err
+	}
+	s.nonce = attrs["r"] + base64.StdEncoding.EncodeToString(nonce)
+	s.saltedPassword = pbkdf2(s.hash, []byte(password), salt, scramIterations)
+	s.serverFirst = "r=" + s.nonce + ",s=" + base64.StdEncoding.EncodeToString(salt) + ",i=" + strconv.Itoa(scramIterations)
+	return []byte(s.serverFirst), false, nil
+}
+
+func (s *scram) final(msg string) ([]byte, bool, error) {
+	i := strings.LastIndex(msg, ",p=")
+	if i < 0 {
+		return nil, false, s.fail("an invalid SCRAM client final message")
+	}
+	withoutProof := msg[:i]
+	attrs := scramAttributes(withoutProof)
+	if attrs["c"] != base64.StdEncoding.EncodeToString([]byte(s.gs2Header)) || attrs["r"] != s.nonce {
+		return nil, false, s.fail("an invalid SCRAM client final message")
+	}
+	proof, err := base64.StdEncoding.DecodeString(msg[i+len(",p="):])
+	if err != nil {
+		return nil, false, s.fail("an invalid SCRAM client final message")
+	}
+
+	authMessage := []byte(s.clientFirstBare + "," + s.serverFirst + "," + withoutProof)
+	clientKey := s.hmac(s.saltedPassword, []byte("Client Key"))
+	storedKey := s.sum(clientKey)
+	signature := s.hmac(storedKey, authMessage)
+	if len(proof) != len(signature) {
+		return nil, false, s.fail(invalidCredentials)
+	}
+	for i := range proof {
+		proof[i] ^= signature[i]
+	}
+	if subtle.ConstantTimeCompare(s.sum(proof), storedKey) != 1 {
+		return nil, false, s.fail(invalidCredentials)
+	}
+
+	serverKey := s.hmac(s.saltedPassword, []byte("Server Key"))
+	s.done = true
+	return []byte("v=" + base64.StdEncoding.EncodeToString(s.hmac(serverKey, authMessage))), true, nil
+}
+
+func (s *scram) fail(reason string) error {
+	return AuthenticationError{Mechanism: s.name, Reason: reason}
+}
+
+func (s *scram) hmac(key, msg []byte) []byte {
+	mac := hmac.New(s.hash, key)
+	mac.Write(msg)
+	return mac.Sum(nil)
+}
+
+func (s *scram) sum(b []byte) []byte {
+	h := s.hash()
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+// scramAttributes parses the comma separated attributes of a SCRAM message
+func scramAttributes(msg string) map[string]string {
+	attrs := make(map[string]string)
+	for _, attr := range strings.Split(msg, ",") {
+		if i := strings.IndexByte(attr, '='); i > 0 {
+			attrs[attr[:i]] = attr[i+1:]
+		}
+	}
+	return attrs
+}
+
+// scramUnescape decodes the commas and equal signs of a SCRAM user name
+func scramUnescape(name string) string {
+	return strings.NewReplacer("=2C", ",", "=3D", "=").Replace(name)
+}
+
+// pbkdf2 derives a key as long as the hash from a password, like Hi() of RFC 5802
+func pbkdf2(h func() hash.Hash, password, salt []byte, iterations int) []byte {
+	mac := hmac.New(h, password)
+	mac.Write(salt)
+	block := make([]byte, 4)
+	binary.BigEndian.PutUint32(block, 1)
+	mac.Write(block)
+	u := mac.Sum(nil)
+	key := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+)
+
+// handlerFunc serves a request, returning an error closes the connection
+type handlerFunc func(b *Broker, conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error
+
+// api is an API served by the broker
+type api struct {
+	name       string
+	minVersion int16
+	maxVersion int16
+	handle     handlerFunc
+}
+
+// apis are the built-in APIs of the broker by key. ApiVersions advertises them, so
+// they must only list the versions the handlers can decode.
+var apis = map[int16]api{
+	protocol.ProduceKey:            {"Produce Message", 0, 9, (*Broker).handleProduce},
+	protocol.FetchKey:              {"Fetch", 0, 12, (*Broker).handleFetch},
+	protocol.OffsetsKey:            {"List Offsets", 0, 7, (*Broker).handleListOffsets},
+	protocol.MetadataKey:           {"Meta Data", 0, 9, (*Broker).handleMetaData},
+	protocol.OffsetCommitKey:       {"Offset Commit", 0, 7, (*Broker).handleOffsetCommit},
+	protocol.OffsetFetchKey:        {"Offset Fetch", 0, 5, (*Broker).handleOffsetFetch},
+	protocol.FindCoordinatorKey:    {"Find Coordinator", 0, 2, (*Broker).handleFindCoordinator},
+	protocol.JoinGroupKey:          {"Join Group", 0, 5, (*Broker).handleJoinGroup},
+	protocol.HeartbeatKey:          {"Heartbeat", 0, 3, (*Broker).handleHeartbeat},
+	protocol.LeaveGroupKey:         {"Leave Group", 0, 3, (*Broker).handleLeaveGroup},
+	protocol.SyncGroupKey:          {"Sync Group", 0, 3, (*Broker).handleSyncGroup},
+	protocol.SaslHandshakeKey:      {"Sasl Handshake", 0, 1, (*Broker).handleSaslHandshake},
+	protocol.APIVersionsKey:        {"Api Version", 0, apiVersionsMaxVersion, (*Broker).handleApiVersion},
+	protocol.CreateTopicsKey:       {"Create Topics", 0, 4, (*Broker).handleCreateTopics},
+	protocol.DeleteTopicsKey:       {"Delete Topics", 0, 3, (*Broker).handleDeleteTopics},
+	protocol.DeleteRecordsKey:      {"Delete Records", 0, 1, (*Broker).handleDeleteRecords},
+	protocol.InitProducerIDKey:     {"Init Producer Id", 0, 1, (*Broker).handleInitProducerID},
+	protocol.AddPartitionsToTxnKey: {"Add Partitions To Txn", 0, 1, (*Broker).handleAddPartitionsToTxn},
+	protocol.AddOffsetsToTxnKey:    {"Add Offsets To Txn", 0, 1, (*Broker).handleAddOffsetsToTxn},
+	protocol.EndTxnKey:             {"End Txn", 0, 1, (*Broker).handleEndTxn},
+	protocol.TxnOffsetCommitKey:    {"Txn Offset Commit", 0, 2, (*Broker).handleTxnOffsetCommit},
+	protocol.SaslAuthenticateKey:   {"Sasl Authenticate", 0, 1, (*Broker).handleSaslAuthenticate},
+	protocol.CreatePartitionsKey:   {"Create Partitions", 0, 1, (*Broker).handleCreatePartitions},
+}
+
+// supportedVersions returns the versions of the built-in APIs, ordered by key
+func supportedVersions() []protocol.APIVersion {
+	versions := make([]protocol.APIVersion, 0, len(apis))
+	for key, a := range apis {
+		versions = append(versions, protocol.APIVersion{APIKey: key, MinVersion: a.minVersion, MaxVersion: a.maxVersion})
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].APIKey < versions[j].APIKey })
+	return versions
+}
+
+// apiVersionsMaxVersion is the latest version of ApiVersions requests the broker can decode
+const apiVersionsMaxVersion = 3
+
+// clientSoftwarePattern is what Kafka accepts as the name and version of the client software
+var clientSoftwarePattern = regexp.MustCompile(`^[a-zA-Z0-9](?:[a-zA-Z0-9\-.]*[a-zA-Z0-9])?$`)
+
+func (b *Broker) handleApiVersion(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	res := &protocol.APIVersionsResponse{APIVersion: header.APIVersion, APIVersions: b.cluster.handlers.versions(b.apiVersions)}
+	// Like Kafka a version the broker does not know is answered in version 0, which
+	// every client can read, with the supported versions
+	if header.APIVersion > apiVersionsMaxVersion {
+		res.APIVersion = 0
+		res.ErrorCode = protocol.ErrUnsupportedVersion.Code()
+		return handleResponse(conn, res, header)
+	}
+
+	req, err := decodeApiVersionRequest(d, header)
+	if err != nil {
+		return err
+	}
+	if err := s.faults.check("", -1); err != nil {
+		res.ErrorCode = errorCode(err)
+		return handleResponse(conn, res, header)
+	}
+	if req.APIVersion >= 3 {
+		if !clientSoftwarePattern.MatchString(req.ClientSoftwareName) || !clientSoftwarePattern.MatchString(req.ClientSoftwareVersion) {
+			res.ErrorCode = protocol.ErrInvalidRequest.Code()
+			return handleResponse(conn, res, header)
+		}
+		s.clientSoftwareName = req.ClientSoftwareName
+		s.clientSoftwareVersion = req.ClientSoftwareVersion
+		fmt.Println("msg", "client software", "name", s.clientSoftwareName, "version", s.clientSoftwareVersion)
+	}
+	return handleResponse(conn, res, header)
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/ninepub/kafka-mock/internal/group"
+	"github.com/ninepub/kafka-mock/internal/protocol"
+	"github.com/ninepub/kafka-mock/internal/storage"
+	"github.com/ninepub/kafka-mock/internal/txn"
+	"github.com/ninepub/kafka-mock/pkg/types"
+)
+
+// Broker serves the connections of one node of the cluster. The state is shared with
+// the other brokers through the cluster.
+type Broker struct {
+	id      int32
+	cluster *Cluster
+	params  *types.Params
+	store   *storage.Store
+	groups  *group.Coordinator
+	txns    *txn.Coordinator
+
+	mu sync.Mutex
+	// listeners are the endpoints of the broker by name
+	listeners map[string]*Listener
+
+	// apiVersions are the versions of the built-in APIs
+	apiVersions []protocol.APIVersion
+
+	done chan struct{}
+}
+
+func newBroker(id int32, c *Cluster) *Broker {
+	return &Broker{
+		id:          id,
+		cluster:     c,
+		params:      c.params,
+		store:       c.store,
+		groups:      c.groups,
+		txns:        c.txns,
+		apiVersions: supportedVersions(),
+		listeners:   make(map[string]*Listener),
+		done:        c.done,
+	}
+}
+
+// ID returns the node ID of the broker
+func (b *Broker) ID() int32 {
+	return b.id
+}
+
+// leads reports whether the broker is the leader of the partition of a log
+func (b *Broker) leads(log *storage.Log) bool {
+	return log.Leader() == b.id
+}
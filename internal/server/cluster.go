@@ -0,0 +1,203 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ninepub/kafka-mock/internal/group"
+	"github.com/ninepub/kafka-mock/internal/storage"
+	"github.com/ninepub/kafka-mock/internal/txn"
+	"github.com/ninepub/kafka-mock/pkg/types"
+)
+
+// Cluster holds the state shared by the brokers of one mock server. Every broker
+// serves the same logs and coordinators, only the partitions they lead differ.
+type Cluster struct {
+	params  *types.Params
+	store   *storage.Store
+	groups  *group.Coordinator
+	txns    *txn.Coordinator
+	brokers []*Broker
+	// handlers are the handlers and middleware registered on top of the built-in APIs
+	handlers registry
+	// faults are injected into the requests of the built-in APIs
+	faults *faults
+	// connFaults disturb the connections of the brokers
+	connFaults *connFaults
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewCluster(params *types.Params) *Cluster {
+	c := &Cluster{
+		params: params,
+		store:  storage.NewStore(),
+		groups: group.NewCoordinator(group.Config{
+			InitialRebalanceDelay: params.GroupInitialRebalanceDelay,
+			MinSessionTimeout:     params.GroupMinSessionTimeout,
+			MaxSessionTimeout:     params.GroupMaxSessionTimeout,
+		}),
+		faults: newFaults(),
+		done:   make(chan struct{}),
+	}
+	c.connFaults = newConnFaults(c.done)
+	c.txns = txn.NewCoordinator(c.writeMarkers)
+	brokers := params.Brokers
+	if brokers <= 0 {
+		brokers = 1
+	}
+	for i := 0; i < brokers; i++ {
+		c.brokers = append(c.brokers, newBroker(int32(i+1), c))
+	}
+	return c
+}
+
+// cleanInterval is how often the logs are cleaned, like log.retention.check.interval.ms
+const cleanInterval = time.Second
+
+// Start creates the topics of the params, adds their faults and starts cleaning the logs
+func (c *Cluster) Start() error {
+	if rf := c.defaultReplicationFactor(); int(rf) > len(c.brokers) {
+		return fmt.Errorf("default replication factor %d larger than the %d brokers", rf, len(c.brokers))
+	}
+	// Faults are only added once every step which may fail succeeded, so that a failed
+	// start can be tried again
+	rules := make([]*faultRule, len(c.params.Faults))
+	for i, f := range c.params.Faults {
+		r, err := newFaultRule(f)
+		if err != nil {
+			return err
+		}
+		rules[i] = r
+	}
+	// Like Kafka the offsets topic keeps the latest commit of each group partition
+	topics := []types.Topic{{
+		Name:       group.OffsetsTopic,
+		Partitions: group.OffsetsTopicPartitions,
+		Configs:    map[string]string{"cleanup.policy": "compact"},
+	}}
+	if c.params.Topic != "" {
+		topics = append(topics, types.Topic{Name: c.params.Topic})
+	}
+	if err := c.CreateTopics(append(topics, c.params.Topics...)); err != nil {
+		return err
+	}
+	for _, r := range rules {
+		c.faults.add(r)
+	}
+	go func() {
+		ticker := time.NewTicker(cleanInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.store.Clean(); err != nil {
+					fmt.Println("msg", "failed to clean logs", "err", err)
+				}
+			case <-c.done:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// CreateTopics creates the given topics. Nothing is created if any of them is invalid.
+func (c *Cluster) CreateTopics(topics []types.Topic) error {
+	configs := make([]*storage.Config, len(topics))
+	for i, t := range topics {
+		if !storage.ValidTopicName(t.Name) {
+			return fmt.Errorf("invalid topic name %q", t.Name)
+		}
+		if t.Partitions < 0 {
+			return fmt.Errorf("invalid partition count %d for topic %s", t.Partitions, t.Name)
+		}
+		if t.ReplicationFactor < 0 || int(t.ReplicationFactor) > len(c.brokers) {
+			return fmt.Errorf("invalid replication factor %d for topic %s with %d brokers", t.ReplicationFactor, t.Name, len(c.brokers))
+		}
+		config, err := storage.NewConfig(t.Configs)
+		if err != nil {
+			return err
+		}
+		configs[i] = config
+	}
+	for i, t := range topics {
+		partitions := t.Partitions
+		if partitions == 0 {
+			partitions = 1
+		}
+		replicationFactor := t.ReplicationFactor
+		if replicationFactor == 0 {
+			replicationFactor = c.defaultReplicationFactor()
+		}
+		c.store.CreateTopic(t.Name, c.assignReplicas(0, partitions, replicationFactor), configs[i])
+	}
+	return nil
+}
+
+// assignReplicas spreads the replicas of the partitions from first up to count over
+// the brokers round robin, so that every broker leads its share of them. The entries
+// of the partitions before first are left nil.
+func (c *Cluster) assignReplicas(first, count int32, replicationFactor int16) [][]int32 {
+	ids := c.brokerIDs()
+	replicas := make([][]int32, count)
+	for p := first; p < count; p++ {
+		for i := 0; i < int(replicationFactor); i++ {
+			replicas[p] = append(replicas[p], ids[(int(p)+i)%len(ids)])
+		}
+	}
+	return replicas
+}
+
+// defaultReplicationFactor returns the replication factor of topics created without one
+func (c *Cluster) defaultReplicationFactor() int16 {
+	if c.params.DefaultReplicationFactor <= 0 {
+		return 1
+	}
+	return c.params.DefaultReplicationFactor
+}
+
+// brokerIDs returns the IDs of the brokers of the cluster
+func (c *Cluster) brokerIDs() []int32 {
+	ids := make([]int32, len(c.brokers))
+	for i, b := range c.brokers {
+		ids[i] = b.id
+	}
+	return ids
+}
+
+// broker returns the broker with the given ID
+func (c *Cluster) broker(id int32) (*Broker, bool) {
+	for _, b := range c.brokers {
+		if b.id == id {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// Brokers returns the brokers of the cluster, ordered by ID
+func (c *Cluster) Brokers() []*Broker {
+	return c.brokers
+}
+
+// Store returns the partition logs of the cluster
+func (c *Cluster) Store() *storage.Store {
+	return c.store
+}
+
+// Groups returns the consumer group coordinator of the cluster
+func (c *Cluster) Groups() *group.Coordinator {
+	return c.groups
+}
+
+// Close aborts requests which are waiting, like long polling fetches and group joins
+func (c *Cluster) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.groups.Close()
+		c.txns.Close()
+	})
+}
@@ -2,11 +2,14 @@
 package server
 
 import (
+	"github.com/ninepub/kafka-mock/internal/group"
 	"github.com/ninepub/kafka-mock/internal/protocol"
 
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"sort"
 )
 
 func decodeHeader(b []byte) (*protocol.RequestHeader, *protocol.ByteDecoder, error) {
@@ -28,6 +31,123 @@ func decodeProduceRequest(d *protocol.ByteDecoder, header *protocol.RequestHeade
 	return req, nil
 }
 
+func decodeFetchRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.FetchRequest, error) {
+	req := &protocol.FetchRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeCreatePartitionsRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.CreatePartitionsRequest, error) {
+	req := &protocol.CreatePartitionsRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeCreateTopicsRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.CreateTopicsRequest, error) {
+	req := &protocol.CreateTopicsRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeDeleteRecordsRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.DeleteRecordsRequest, error) {
+	req := &protocol.DeleteRecordsRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeDeleteTopicsRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.DeleteTopicsRequest, error) {
+	req := &protocol.DeleteTopicsRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeInitProducerIDRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.InitProducerIDRequest, error) {
+	req := &protocol.InitProducerIDRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeAddPartitionsToTxnRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.AddPartitionsToTxnRequest, error) {
+	req := &protocol.AddPartitionsToTxnRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeAddOffsetsToTxnRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.AddOffsetsToTxnRequest, error) {
+	req := &protocol.AddOffsetsToTxnRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeEndTxnRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.EndTxnRequest, error) {
+	req := &protocol.EndTxnRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeTxnOffsetCommitRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.TxnOffsetCommitRequest, error) {
+	req := &protocol.TxnOffsetCommitRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeSaslHandshakeRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.SaslHandshakeRequest, error) {
+	req := &protocol.SaslHandshakeRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeSaslAuthenticateRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.SaslAuthenticateRequest, error) {
+	req := &protocol.SaslAuthenticateRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeListOffsetsRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.ListOffsetsRequest, error) {
+	req := &protocol.ListOffsetsRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
 func decodeMetadataRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.MetadataRequest, error) {
 	req := &protocol.MetadataRequest{}
 	if err := req.Decode(d, header.APIVersion); err != nil {
@@ -46,6 +166,69 @@ func decodeApiVersionRequest(d *protocol.ByteDecoder, header *protocol.RequestHe
 	return req, nil
 }
 
+func decodeFindCoordinatorRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.FindCoordinatorRequest, error) {
+	req := &protocol.FindCoordinatorRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeJoinGroupRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.JoinGroupRequest, error) {
+	req := &protocol.JoinGroupRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeSyncGroupRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.SyncGroupRequest, error) {
+	req := &protocol.SyncGroupRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeHeartbeatRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.HeartbeatRequest, error) {
+	req := &protocol.HeartbeatRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeLeaveGroupRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.LeaveGroupRequest, error) {
+	req := &protocol.LeaveGroupRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeOffsetCommitRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.OffsetCommitRequest, error) {
+	req := &protocol.OffsetCommitRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeOffsetFetchRequest(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.OffsetFetchRequest, error) {
+	req := &protocol.OffsetFetchRequest{}
+	if err := req.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode request", "err", err)
+		return nil, err
+	}
+	return req, nil
+}
+
 func decodeProduceResponse(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.ProduceResponse, error) {
 	res := &protocol.ProduceResponse{}
 	if err := res.Decode(d, header.APIVersion); err != nil {
@@ -55,6 +238,15 @@ func decodeProduceResponse(d *protocol.ByteDecoder, header *protocol.RequestHead
 	return res, nil
 }
 
+func decodeFetchResponse(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.FetchResponse, error) {
+	res := &protocol.FetchResponse{}
+	if err := res.Decode(d, header.APIVersion); err != nil {
+		fmt.Println("msg", "failed to decode response", "err", err)
+		return nil, err
+	}
+	return res, nil
+}
+
 func decodeMetadataResponse(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.MetadataResponse, error) {
 	res := &protocol.MetadataResponse{}
 	if err := res.Decode(d, header.APIVersion); err != nil {
@@ -65,11 +257,6 @@ func decodeMetadataResponse(d *protocol.ByteDecoder, header *protocol.RequestHea
 }
 
 func decodeApiVersionResponse(d *protocol.ByteDecoder, header *protocol.RequestHeader) (*protocol.APIVersionsResponse, error) {
-	_, err := d.Int16()
-
-	if err != nil {
-		return nil, err
-	}
 	res := &protocol.APIVersionsResponse{}
 	if err := res.Decode(d, header.APIVersion); err != nil {
 		fmt.Println("msg", "failed to decode response", "err", err)
@@ -78,26 +265,96 @@ func decodeApiVersionResponse(d *protocol.ByteDecoder, header *protocol.RequestH
 	return res, nil
 }
 
-func getRecords(data *protocol.ProduceRequest) map[string][]byte {
-	records := make(map[string][]byte)
-	for topic, topicData := range data.Records {
-		fmt.Println("Topic :", topic)
-		for r, topicBatch := range topicData {
-			fmt.Println("Topic record number  :", r+1)
-			if topicBatch.RecordsType == 1 {
-				fmt.Println("Legacy record type....")
-				for _, msg := range topicBatch.MsgSet.Messages {
-					records[string(msg.Msg.Key)] = msg.Msg.Value
-				}
-			} else {
-				fmt.Println("Default record type....")
-				for _, msg := range topicBatch.RecordBatch.Records {
-					records[string(msg.Key)] = msg.Value
-				}
-			}
-		}
-	}
-	return records
+// sortedTopics returns the topics of a produce request in a stable order
+func sortedTopics(records map[string]map[int32]protocol.Records) []string {
+	topics := make([]string, 0, len(records))
+	for topic := range records {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// sortedPartitions returns the partitions of a produce request topic in a stable order
+func sortedPartitions(records map[int32]protocol.Records) []int32 {
+	partitions := make([]int32, 0, len(records))
+	for partition := range records {
+		partitions = append(partitions, partition)
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+	return partitions
+}
+
+// sortedOffsetTopics returns the topics of committed offsets in a stable order
+func sortedOffsetTopics(offsets map[string]map[int32]*group.OffsetAndMetadata) []string {
+	topics := make([]string, 0, len(offsets))
+	for topic := range offsets {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// sortedOffsetPartitions returns the partitions of committed offsets in a stable order
+func sortedOffsetPartitions(offsets map[int32]*group.OffsetAndMetadata) []int32 {
+	partitions := make([]int32, 0, len(offsets))
+	for partition := range offsets {
+		partitions = append(partitions, partition)
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+	return partitions
+}
+
+// sortedTxnTopics returns the topics of transaction partitions in a stable order
+func sortedTxnTopics(partitions map[string][]int32) []string {
+	topics := make([]string, 0, len(partitions))
+	for topic := range partitions {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// stringValue returns the value of a nullable string, or an empty string for null
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// nullableString returns null for an empty string
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// apiError is a protocol error with a message for the responses which carry one
+type apiError struct {
+	err     protocol.Error
+	message string
+}
+
+func newAPIError(err protocol.Error, format string, args ...interface{}) error {
+	return apiError{err: err, message: fmt.Sprintf(format, args...)}
+}
+
+func (e apiError) Error() string {
+	return e.message
+}
+
+func (e apiError) Unwrap() error {
+	return e.err
+}
+
+func errorCode(err error) int16 {
+	var kerr protocol.Error
+	if errors.As(err, &kerr) {
+		return kerr.Code()
+	}
+	return protocol.ErrUnknown.Code()
 }
 
 func WriteToJson(data interface{}, path string) error {
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ninepub/kafka-mock/pkg/types"
+)
+
+// errClosedMidResponse closes a connection after half of a response was written
+var errClosedMidResponse = errors.New("connection closed mid-response by a fault")
+
+// errHalfFrame closes a connection whose client gave up on a response cut in half
+var errHalfFrame = errors.New("connection closed after half a response by a fault")
+
+// errClosing aborts the responses delayed by faults or rebalances when the cluster is closed
+var errClosing = errors.New("cluster closed")
+
+// connFaults are the faults disturbing the connections of the cluster
+type connFaults struct {
+	mu     sync.Mutex
+	config types.ConnectionFaults
+	// refuseUntil is when connections stop being refused
+	refuseUntil time.Time
+	rand        *rand.Rand
+
+	done chan struct{}
+}
+
+func newConnFaults(done chan struct{}) *connFaults {
+	return &connFaults{rand: rand.New(rand.NewSource(time.Now().UnixNano())), done: done}
+}
+
+func (f *connFaults) set(config types.ConnectionFaults) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.config = config
+}
+
+func (f *connFaults) get() types.ConnectionFaults {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.config
+}
+
+func (f *connFaults) refuse(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.refuseUntil = time.Now().Add(d)
+}
+
+func (f *connFaults) refusing() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return time.Now().Before(f.refuseUntil)
+}
+
+// latency returns how long to wait before the next response
+func (f *connFaults) latency() time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	latency := f.config.Latency
+	if f.config.Jitter > 0 {
+		latency += time.Duration(f.rand.Int63n(int64(f.config.Jitter) + 1))
+	}
+	return latency
+}
+
+// closeAfter reports whether a connection which served that many requests is closed
+func (f *connFaults) closeAfter(requests int) bool {
+	n := f.get().CloseAfterRequests
+	return n > 0 && requests >= n
+}
+
+// write writes a response to a connection, delayed, throttled or cut off by the faults
+func (f *connFaults) write(conn net.Conn, p []byte) (int, error) {
+	if latency := f.latency(); latency > 0 && !f.sleep(latency) {
+		return 0, errClosing
+	}
+	config := f.get()
+	switch {
+	case config.CloseMidResponse:
+		n, err := conn.Write(p[:len(p)/2])
+		conn.Close()
+		if err != nil {
+			return n, err
+		}
+		return n, errClosedMidResponse
+	case config.HalfFrame:
+		n, err := conn.Write(p[:len(p)/2])
+		if err != nil {
+			return n, err
+		}
+		f.stall(conn)
+		return n, errHalfFrame
+	case config.Bandwidth > 0:
+		return f.throttle(conn, p, config.Bandwidth)
+	default:
+		return conn.Write(p)
+	}
+}
+
+// stall writes nothing more to a connection, whose responses would be misframed, and
+// waits for the client to close it or the cluster to be closed
+func (f *connFaults) stall(conn net.Conn) {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-f.done:
+			conn.Close()
+		case <-stop:
+		}
+	}()
+	io.Copy(ioutil.Discard, conn)
+}
+
+// throttle writes p in chunks of a tenth of the bandwidth, each followed by the time
+// it takes to send it
+func (f *connFaults) throttle(conn net.Conn, p []byte, bandwidth int) (int, error) {
+	chunk := bandwidth / 10
+	if chunk == 0 {
+		chunk = 1
+	}
+	written := 0
+	for written < len(p) {
+		end := written + chunk
+		if end > len(p) {
+			end = len(p)
+		}
+		n, err := conn.Write(p[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if !f.sleep(time.Duration(n) * time.Second / time.Duration(bandwidth)) {
+			return written, errClosing
+		}
+	}
+	return written, nil
+}
+
+// sleep waits for the given duration, it returns false if the cluster closed first
+func (f *connFaults) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-f.done:
+		return false
+	}
+}
+
+// SetConnectionFaults replaces the faults disturbing the connections of every broker
+func (c *Cluster) SetConnectionFaults(config types.ConnectionFaults) {
+	c.connFaults.set(config)
+}
+
+// RefuseConnections closes the connections accepted during the given period
+func (c *Cluster) RefuseConnections(d time.Duration) {
+	c.connFaults.refuse(d)
+}
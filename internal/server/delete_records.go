@@ -0,0 +1,47 @@
+package server
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+)
+
+func (b *Broker) handleDeleteRecords(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeDeleteRecordsRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	res := &protocol.DeleteRecordsResponse{APIVersion: header.APIVersion}
+	for _, t := range req.Topics {
+		topicResult := &protocol.DeleteRecordsTopicResult{Name: t.Name}
+		res.Topics = append(res.Topics, topicResult)
+		for _, p := range t.Partitions {
+			pr := &protocol.DeleteRecordsPartitionResult{Partition: p.Partition, LowWatermark: -1}
+			topicResult.Partitions = append(topicResult.Partitions, pr)
+
+			if err := s.faults.check(t.Name, p.Partition); err != nil {
+				pr.ErrorCode = errorCode(err)
+				continue
+			}
+			log, ok := b.store.Log(t.Name, p.Partition)
+			if !ok {
+				pr.ErrorCode = protocol.ErrUnknownTopicOrPartition.Code()
+				continue
+			}
+			if !b.leads(log) {
+				pr.ErrorCode = protocol.ErrNotLeaderForPartition.Code()
+				continue
+			}
+			lowWatermark, err := log.DeleteRecords(p.Offset)
+			if err != nil {
+				pr.ErrorCode = errorCode(err)
+				continue
+			}
+			fmt.Println("msg", "deleted records", "topic", t.Name, "partition", p.Partition, "log start offset", lowWatermark)
+			pr.LowWatermark = lowWatermark
+		}
+	}
+	return handleResponse(conn, res, header)
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+	"github.com/ninepub/kafka-mock/pkg/types"
+)
+
+// faultRule is a fault along with how often it matched and failed requests
+type faultRule struct {
+	types.Fault
+	// key is the key of the API matched, -1 for any
+	key int16
+	err protocol.Error
+
+	matched  int
+	injected int
+}
+
+// newFaultRule validates a fault
+func newFaultRule(f types.Fault) (*faultRule, error) {
+	r := &faultRule{Fault: f, key: -1}
+	if f.API != "" {
+		key, ok := protocol.APIKeyByName(f.API)
+		if !ok {
+			n, err := strconv.ParseInt(f.API, 10, 16)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("unknown API %q", f.API)
+			}
+			key = int16(n)
+		}
+		r.key = key
+	}
+
+	var ok bool
+	if r.err, ok = protocol.ErrorByName(f.Error); !ok {
+		code, err := strconv.ParseInt(f.Error, 10, 16)
+		if err == nil {
+			r.err, ok = protocol.Errs[int16(code)]
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown error %q", f.Error)
+		}
+	}
+	if r.err == protocol.ErrNone {
+		return nil, fmt.Errorf("fault without error")
+	}
+
+	switch f.Mode {
+	case "":
+		r.Mode = types.FaultOnce
+	case types.FaultOnce, types.FaultAlways:
+	case types.FaultTimes:
+		if f.Times <= 0 {
+			return nil, fmt.Errorf("invalid fault times %d", f.Times)
+		}
+	case types.FaultProbability:
+		if f.Probability <= 0 || f.Probability > 1 {
+			return nil, fmt.Errorf("invalid fault probability %g", f.Probability)
+		}
+	default:
+		return nil, fmt.Errorf("unknown fault mode %q", f.Mode)
+	}
+	if f.Request < 0 {
+		return nil, fmt.Errorf("invalid fault request %d", f.Request)
+	}
+	return r, nil
+}
+
+// matches reports whether the rule applies to a topic partition of a request, or to
+// the request itself when topic is empty
+func (r *faultRule) matches(topic string, partition int32) bool {
+	if r.Topic != "" && r.Topic != topic {
+		return false
+	}
+	if len(r.Partitions) == 0 {
+		return true
+	}
+	for _, p := range r.Partitions {
+		if p == partition && topic != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// faults are the faults injected into the requests of the cluster
+type faults struct {
+	mu    sync.Mutex
+	rules []*faultRule
+	rand  *rand.Rand
+}
+
+func newFaults() *faults {
+	return &faults{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (f *faults) add(rule *faultRule) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, rule)
+}
+
+func (f *faults) clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = nil
+}
+
+// request returns the faults which may apply to a request, nil if none does
+func (f *faults) request(header *protocol.RequestHeader) *faultRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rules []*faultRule
+	for _, r := range f.rules {
+		if (r.key == -1 || r.key == header.APIKey) && (r.ClientID == "" || r.ClientID == header.ClientID) {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	return &faultRequest{faults: f, header: header, rules: rules, decided: make(map[*faultRule]bool)}
+}
+
+// inject counts a request matching a rule and decides whether it fails
+func (f *faults) inject(r *faultRule) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r.matched++
+	if r.matched < r.Request {
+		return false
+	}
+	switch r.Mode {
+	case types.FaultOnce:
+		if r.injected >= 1 {
+			return false
+		}
+	case types.FaultTimes:
+		if r.injected >= r.Times {
+			return false
+		}
+	case types.FaultProbability:
+		if f.rand.Float64() >= r.Probability {
+			return false
+		}
+	}
+	r.injected++
+	return true
+}
+
+// faultRequest decides which faults fail a request. A rule is counted once per
+// request, however many of its topics and partitions it matches.
+type faultRequest struct {
+	faults  *faults
+	header  *protocol.RequestHeader
+	rules   []*faultRule
+	decided map[*faultRule]bool
+}
+
+// check returns the error injected into a topic partition of the request, or into
+// the request itself when topic is empty and partition -1
+func (fr *faultRequest) check(topic string, partition int32) error {
+	if fr == nil {
+		return nil
+	}
+	for _, r := range fr.rules {
+		if !r.matches(topic, partition) {
+			continue
+		}
+		inject, ok := fr.decided[r]
+		if !ok {
+			inject = fr.faults.inject(r)
+			fr.decided[r] = inject
+		}
+		if inject {
+			fmt.Println("msg", "injected fault", "key", fr.header.APIKey, "topic", topic, "partition", partition, "err", r.err)
+			return r.err
+		}
+	}
+	return nil
+}
+
+// AddFault starts injecting a fault into the requests of every broker
+func (c *Cluster) AddFault(f types.Fault) error {
+	r, err := newFaultRule(f)
+	if err != nil {
+		return err
+	}
+	c.faults.add(r)
+	return nil
+}
+
+// ClearFaults stops injecting faults
+func (c *Cluster) ClearFaults() {
+	c.faults.clear()
+}
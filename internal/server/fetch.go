@@ -0,0 +1,137 @@
+package server
+
+import (
+	"math"
+	"time"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+)
+
+// fetch reads the requested partitions, waiting up to MaxWaitTime for at least
+// MinBytes of records to become available like a real broker does
+func (b *Broker) fetch(req *protocol.FetchRequest, faults *faultRequest) *protocol.FetchResponse {
+	deadline := time.Now().Add(time.Duration(req.MaxWaitTime) * time.Millisecond)
+	for {
+		changed := b.store.Changed()
+		res, size, failed := b.readFetch(req, faults)
+		if failed || size >= int(req.MinBytes) {
+			return res
+		}
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return res
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-changed:
+			timer.Stop()
+		case <-timer.C:
+		case <-b.done:
+			timer.Stop()
+			return res
+		}
+	}
+}
+
+// readFetch builds a fetch response from the current state of the logs. It returns the
+// number of record bytes read and whether any partition failed.
+func (b *Broker) readFetch(req *protocol.FetchRequest, faults *faultRequest) (*protocol.FetchResponse, int, bool) {
+	res := &protocol.FetchResponse{APIVersion: req.APIVersion}
+
+	// Fetch sessions are never created, so clients can only send full fetch requests
+	if req.SessionID != 0 {
+		res.ErrorCode = protocol.ErrFetchSessionIdNotFound.Code()
+		return res, 0, true
+	}
+
+	// Versions before 3 have no response size limit and never force the first message
+	maxBytes := int32(math.MaxInt32)
+	minOneMessage := false
+	if req.APIVersion >= 3 {
+		maxBytes = req.MaxBytes
+		minOneMessage = true
+	}
+
+	size := 0
+	failed := false
+	for _, t := range req.Topics {
+		topicResponse := &protocol.FetchTopicResponse{Topic: t.Topic}
+		for _, p := range t.Partitions {
+			pr := &protocol.FetchPartitionResponse{
+				Partition:            p.Partition,
+				HighWatermark:        -1,
+				LastStableOffset:     -1,
+				LogStartOffset:       -1,
+				PreferredReadReplica: -1,
+				Records:              []byte{},
+			}
+			topicResponse.PartitionResponses = append(topicResponse.PartitionResponses, pr)
+
+			if err := faults.check(t.Topic, p.Partition); err != nil {
+				pr.ErrorCode = errorCode(err)
+				failed = true
+				continue
+			}
+			log, ok := b.store.Log(t.Topic, p.Partition)
+			if !ok {
+				pr.ErrorCode = protocol.ErrUnknownTopicOrPartition.Code()
+				failed = true
+				continue
+			}
+			if !b.leads(log) {
+				pr.ErrorCode = protocol.ErrNotLeaderForPartition.Code()
+				failed = true
+				continue
+			}
+			// The mock never changes leaders, so the leader epoch is always 0
+			if p.CurrentLeaderEpoch > 0 {
+				pr.ErrorCode = protocol.ErrUnknownLeaderEpoch.Code()
+				failed = true
+				continue
+			}
+
+			pr.HighWatermark = log.HighWatermark()
+			pr.LastStableOffset = log.LastStableOffset()
+			pr.LogStartOffset = log.LogStartOffset()
+
+			// read_committed consumers only get records below the last stable offset,
+			// along with the aborted transactions among them
+			maxOffset := pr.HighWatermark
+			if req.IsolationLevel == protocol.ReadCommitted {
+				maxOffset = pr.LastStableOffset
+				pr.AbortedTransactions = log.AbortedTransactions(p.FetchOffset, maxOffset)
+			}
+
+			limit := p.MaxBytes
+			if maxBytes < limit {
+				limit = maxBytes
+			}
+			records, err := log.Read(p.FetchOffset, maxOffset, limit, minOneMessage, fetchMagic(req.APIVersion))
+			if err != nil {
+				pr.ErrorCode = errorCode(err)
+				failed = true
+				continue
+			}
+			if len(records) > 0 {
+				minOneMessage = false
+				maxBytes -= int32(len(records))
+				size += len(records)
+			}
+			pr.Records = records
+		}
+		res.Responses = append(res.Responses, topicResponse)
+	}
+	return res, size, failed
+}
+
+// fetchMagic returns the newest message format understood by a fetch version
+func fetchMagic(version int16) int8 {
+	switch {
+	case version >= 4:
+		return 2
+	case version >= 2:
+		return 1
+	default:
+		return 0
+	}
+}
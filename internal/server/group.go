@@ -0,0 +1,220 @@
+package server
+
+import (
+	"net"
+	"time"
+
+	"github.com/ninepub/kafka-mock/internal/group"
+	"github.com/ninepub/kafka-mock/internal/protocol"
+)
+
+func (b *Broker) handleFindCoordinator(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeFindCoordinatorRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	res := &protocol.FindCoordinatorResponse{APIVersion: header.APIVersion}
+	err = s.faults.check("", -1)
+	switch {
+	case err != nil:
+	case req.CoordinatorType == protocol.CoordinatorTransaction && req.CoordinatorKey == "":
+		err = protocol.ErrInvalidRequest
+	case req.CoordinatorType == protocol.CoordinatorTransaction:
+	case req.CoordinatorType != protocol.CoordinatorGroup:
+		err = protocol.ErrInvalidRequest
+	case req.CoordinatorKey == "":
+		err = protocol.ErrInvalidGroupId
+	}
+	if err == nil {
+		var coordinator *Broker
+		var l *Listener
+		if coordinator, l, err = b.coordinator(req.CoordinatorKey, s.listener.Name); err == nil {
+			res.NodeID = coordinator.id
+			res.Host = l.Host
+			res.Port = l.Port
+		}
+	}
+	if err != nil {
+		msg := err.Error()
+		res.ErrorCode = errorCode(err)
+		res.ErrorMessage = &msg
+		res.NodeID = -1
+		res.Host = ""
+		res.Port = -1
+	}
+	return handleResponse(conn, res, header)
+}
+
+// coordinator returns the broker coordinating a group or a transactional ID, the leader
+// of the partition of the offsets topic the key maps to, along with its endpoint on the
+// listener of the request
+func (b *Broker) coordinator(key, listener string) (*Broker, *Listener, error) {
+	coordinator := b
+	if partition, ok := b.offsetsPartition(key); ok {
+		if log, ok := b.store.Log(group.OffsetsTopic, partition); ok {
+			coordinator, ok = b.cluster.broker(log.Leader())
+			if !ok {
+				return nil, nil, protocol.ErrCoordinatorNotAvailable
+			}
+		}
+	}
+	l, ok := coordinator.Listener(listener)
+	if !ok {
+		return nil, nil, protocol.ErrCoordinatorNotAvailable
+	}
+	return coordinator, l, nil
+}
+
+func (b *Broker) handleJoinGroup(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeJoinGroupRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	res := &protocol.JoinGroupResponse{APIVersion: header.APIVersion, GenerationID: -1, MemberID: req.MemberID}
+	if err := s.faults.check("", -1); err != nil {
+		res.ErrorCode = errorCode(err)
+		return handleResponse(conn, res, header)
+	}
+
+	join := &group.JoinRequest{
+		GroupID:              req.GroupID,
+		MemberID:             req.MemberID,
+		GroupInstanceID:      stringValue(req.GroupInstanceID),
+		ClientID:             header.ClientID,
+		ClientHost:           remoteHost(conn),
+		SessionTimeout:       time.Duration(req.SessionTimeout) * time.Millisecond,
+		RebalanceTimeout:     time.Duration(req.RebalanceTimeout) * time.Millisecond,
+		ProtocolType:         req.ProtocolType,
+		RequireKnownMemberID: header.APIVersion >= 4,
+	}
+	for _, p := range req.GroupProtocols {
+		join.Protocols = append(join.Protocols, group.Protocol{Name: p.Name, Metadata: p.Metadata})
+	}
+
+	// The response is delayed until the rebalance completes
+	var result *group.JoinResult
+	select {
+	case result = <-b.groups.Join(join):
+	case <-b.done:
+		return errClosing
+	}
+
+	res.GenerationID = result.GenerationID
+	res.GroupProtocol = result.Protocol
+	res.LeaderID = result.LeaderID
+	res.MemberID = result.MemberID
+	if result.Err != nil {
+		res.ErrorCode = errorCode(result.Err)
+	}
+	for _, m := range result.Members {
+		res.Members = append(res.Members, &protocol.GroupMember{
+			MemberID:        m.MemberID,
+			GroupInstanceID: nullableString(m.GroupInstanceID),
+			Metadata:        m.Metadata,
+		})
+	}
+	return handleResponse(conn, res, header)
+}
+
+func (b *Broker) handleSyncGroup(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeSyncGroupRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	res := &protocol.SyncGroupResponse{APIVersion: header.APIVersion, Assignment: []byte{}}
+	if err := s.faults.check("", -1); err != nil {
+		res.ErrorCode = errorCode(err)
+		return handleResponse(conn, res, header)
+	}
+
+	sync := &group.SyncRequest{
+		GroupID:         req.GroupID,
+		GenerationID:    req.GenerationID,
+		MemberID:        req.MemberID,
+		GroupInstanceID: stringValue(req.GroupInstanceID),
+		Assignments:     make(map[string][]byte),
+	}
+	for _, a := range req.Assignments {
+		sync.Assignments[a.MemberID] = a.Assignment
+	}
+
+	// Followers wait for the leader to send the assignments
+	var result *group.SyncResult
+	select {
+	case result = <-b.groups.Sync(sync):
+	case <-b.done:
+		return errClosing
+	}
+
+	res.Assignment = result.Assignment
+	if result.Err != nil {
+		res.ErrorCode = errorCode(result.Err)
+	}
+	return handleResponse(conn, res, header)
+}
+
+func (b *Broker) handleHeartbeat(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeHeartbeatRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	res := &protocol.HeartbeatResponse{APIVersion: header.APIVersion}
+	if err := s.faults.check("", -1); err != nil {
+		res.ErrorCode = errorCode(err)
+		return handleResponse(conn, res, header)
+	}
+	if err := b.groups.Heartbeat(req.GroupID, req.GenerationID, req.MemberID, stringValue(req.GroupInstanceID)); err != nil {
+		res.ErrorCode = errorCode(err)
+	}
+	return handleResponse(conn, res, header)
+}
+
+func (b *Broker) handleLeaveGroup(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeLeaveGroupRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	res := &protocol.LeaveGroupResponse{APIVersion: header.APIVersion}
+	if err := s.faults.check("", -1); err != nil {
+		res.ErrorCode = errorCode(err)
+		return handleResponse(conn, res, header)
+	}
+
+	// Versions before 3 remove a single dynamic member
+	members := []*group.LeavingMember{{MemberID: req.MemberID}}
+	if header.APIVersion >= 3 {
+		members = make([]*group.LeavingMember, len(req.Members))
+		for i, m := range req.Members {
+			members[i] = &group.LeavingMember{MemberID: m.MemberID, GroupInstanceID: stringValue(m.GroupInstanceID)}
+		}
+	}
+	errs := b.groups.Leave(req.GroupID, members)
+
+	if header.APIVersion < 3 {
+		if errs[0] != nil {
+			res.ErrorCode = errorCode(errs[0])
+		}
+	} else {
+		for i, m := range req.Members {
+			mr := &protocol.LeaveGroupMemberResponse{MemberID: m.MemberID, GroupInstanceID: m.GroupInstanceID}
+			if errs[i] != nil {
+				mr.ErrorCode = errorCode(errs[i])
+			}
+			res.Members = append(res.Members, mr)
+		}
+	}
+	return handleResponse(conn, res, header)
+}
+
+func remoteHost(conn net.Conn) string {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return conn.RemoteAddr().String()
+	}
+	return host
+}
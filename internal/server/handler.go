@@ -2,8 +2,10 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"github.com/ninepub/kafka-mock/internal/message"
 	"github.com/ninepub/kafka-mock/internal/protocol"
 	"github.com/ninepub/kafka-mock/pkg/types"
 	"io"
@@ -11,34 +13,165 @@ import (
 	"time"
 )
 
-func handleProduce(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, data chan map[string][]byte) {
+func (b *Broker) handleProduce(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
 	req, err := decodeProduceRequest(d, header)
 	if err != nil {
-		fmt.Println("msg", "failed to decode request", "err", err)
+		return err
 	}
-	records := getRecords(req)
 
-	offset := message.ProduceResponse.Responses[0].PartitionResponses[0].BaseOffset
-	message.ProduceResponse.Responses[0].PartitionResponses[0].BaseOffset = offset + 1
-	message.ProduceResponse.Responses[0].PartitionResponses[0].LogAppendTime = time.Now()
-	handleResponse(conn, message.ProduceResponse, header)
+	res := &protocol.ProduceResponse{APIVersion: header.APIVersion}
+	var records []*types.Record
+	failed := false
+	for _, topic := range sortedTopics(req.Records) {
+		topicResponse := &protocol.ProduceTopicResponse{Topic: topic}
+		for _, partition := range sortedPartitions(req.Records[topic]) {
+			batch := req.Records[topic][partition]
+			pr := &protocol.ProducePartitionResponse{Partition: partition, BaseOffset: -1, LogStartOffset: -1}
+			topicResponse.PartitionResponses = append(topicResponse.PartitionResponses, pr)
 
-	data <- records
+			if err := b.checkRequiredAcks(req.RequiredAcks); err != nil {
+				pr.ErrorCode = errorCode(err)
+				failed = true
+				continue
+			}
+			if err := s.faults.check(topic, partition); err != nil {
+				pr.ErrorCode = errorCode(err)
+				failed = true
+				continue
+			}
+			if log, ok := b.store.Log(topic, partition); ok && !b.leads(log) {
+				pr.ErrorCode = protocol.ErrNotLeaderForPartition.Code()
+				failed = true
+				continue
+			}
+			if err := b.checkTransaction(req, &batch, topic, partition); err != nil {
+				pr.ErrorCode = errorCode(err)
+				failed = true
+				continue
+			}
+			info, err := b.store.Append(topic, partition, &batch)
+			if err != nil {
+				fmt.Println("msg", "failed to append records", "topic", topic, "partition", partition, "err", err)
+				pr.ErrorCode = errorCode(err)
+				failed = true
+				continue
+			}
+			if info.Duplicate {
+				fmt.Println("Topic :", topic, "partition :", partition, "duplicate batch at offset :", info.FirstOffset)
+			} else {
+				fmt.Println("Topic :", topic, "partition :", partition, "records :", len(info.Records))
+			}
+			pr.BaseOffset = info.FirstOffset
+			pr.LogStartOffset = info.LogStartOffset
+			pr.LogAppendTime = info.LogAppendTime
+			records = append(records, info.Records...)
+		}
+		res.Responses = append(res.Responses, topicResponse)
+	}
+
+	if req.RequiredAcks == -1 && !failed {
+		if err := b.awaitReplication(req.Timeout); err != nil {
+			for _, topicResponse := range res.Responses {
+				for _, pr := range topicResponse.PartitionResponses {
+					pr.ErrorCode = errorCode(err)
+				}
+			}
+		}
+	}
+
+	if len(records) > 0 {
+		defer b.publish(records)
+	}
+
+	// With acks=0 the client expects no response, a broker closes the connection instead
+	// to signal that the records were not appended
+	if req.RequiredAcks == 0 {
+		if failed {
+			return errors.New("failed to append records of an acks=0 produce request")
+		}
+		return nil
+	}
+	return handleResponse(conn, res, header)
 }
 
-func handleMetaData(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader) {
-	// Handle the Api version request if required here for now we are ignoring the request...
+// publish sends produced records to the channels of the params. Like the sends of
+// the broker before, it blocks until they are received or the broker is closed.
+func (b *Broker) publish(records []*types.Record) {
+	if data := b.params.Data; data != nil {
+		values := make(map[string][]byte, len(records))
+		for _, r := range records {
+			values[string(r.Key)] = r.Value
+		}
+		select {
+		case data <- values:
+		case <-b.done:
+			return
+		}
+	}
+	if ch := b.params.Records; ch != nil {
+		select {
+		case ch <- records:
+		case <-b.done:
+		}
+	}
+}
 
-	// Modify the response structure here before sending to client
-	handleResponse(conn, message.MetadataResponse, header)
+// checkTransaction verifies that a transactional batch belongs to the ongoing
+// transaction of its producer and that the partition was added to it, otherwise
+// EndTxn would never write the marker which completes the batch
+func (b *Broker) checkTransaction(req *protocol.ProduceRequest, records *protocol.Records, topic string, partition int32) error {
+	batch := records.RecordBatch
+	if batch == nil || !batch.IsTransactional {
+		return nil
+	}
+	if req.TransactionalID == nil {
+		return protocol.ErrInvalidTxnState
+	}
+	return b.txns.Check(*req.TransactionalID, batch.ProducerID, batch.ProducerEpoch, topic, partition)
 }
 
-func handleApiVersion(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader) {
-	// Handle the Api version request if required here for now we are ignoring the request...
+// checkRequiredAcks validates the acks of a produce request before its records are appended
+func (b *Broker) checkRequiredAcks(acks int16) error {
+	switch acks {
+	case 0, 1:
+		return nil
+	case -1:
+		if b.params.ReplicationFailure == types.ReplicationNotEnoughReplicas {
+			return protocol.ErrNotEnoughReplicas
+		}
+		return nil
+	default:
+		return protocol.ErrInvalidRequiredAcks
+	}
+}
 
-	// Modify the response structure here before sending to client
+// awaitReplication simulates the wait for the followers of an acks=-1 produce request
+func (b *Broker) awaitReplication(timeout int32) error {
+	limit := time.Duration(timeout) * time.Millisecond
+	if b.params.ReplicationFailure == types.ReplicationTimeout || b.params.ReplicationDelay > limit {
+		b.sleep(limit)
+		return protocol.ErrRequestTimedOut
+	}
+	b.sleep(b.params.ReplicationDelay)
+	return nil
+}
 
-	handleResponse(conn, message.APIVersionsResponse, header)
+// sleep waits for the given duration or until the broker is closed
+func (b *Broker) sleep(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-b.done:
+	}
+}
+
+func (b *Broker) handleFetch(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeFetchRequest(d, header)
+	if err != nil {
+		return err
+	}
+	return handleResponse(conn, b.fetch(req, s.faults), header)
 }
 
 func encodeResponse(res interface{}) ([]byte, error) {
@@ -49,9 +182,10 @@ func encodeResponse(res interface{}) ([]byte, error) {
 	return b, nil
 }
 
-func handleResponse(conn net.Conn, res protocol.ResponseBody, header *protocol.RequestHeader) error {
+func handleResponse(w io.Writer, res protocol.ResponseBody, header *protocol.RequestHeader) error {
 	response := &protocol.Response{
 		CorrelationID: header.CorrelationID,
+		HeaderVersion: header.ResponseHeaderVersion(),
 		Body:          res,
 	}
 
@@ -59,16 +193,52 @@ func handleResponse(conn net.Conn, res protocol.ResponseBody, header *protocol.R
 	if err != nil {
 		panic(err)
 	}
-	_, err = conn.Write(b)
+	_, err = w.Write(b)
 	return err
 }
 
-func HandleConnection(conn net.Conn, params *types.Params) {
+// tlsHandshakeTimeout bounds how long clients may take to complete the TLS handshake
+const tlsHandshakeTimeout = 10 * time.Second
+
+// HandleConnection serves the requests of a client connected to a listener until it
+// disconnects or the broker is closed. The connection is wrapped in TLS here when the
+// listener serves TLS.
+func (b *Broker) HandleConnection(conn net.Conn, l *Listener) {
 	remoteAddr := conn.RemoteAddr().String()
 	fmt.Println("Client connected from " + remoteAddr)
 
-	defer conn.Close()
+	defer func() {
+		conn.Close()
+		fmt.Println("Client at " + remoteAddr + " disconnected.")
+	}()
+
+	// A reset, rather than an orderly close, looks to the client like a refused
+	// connection. It happens before any TLS handshake, on the plain TCP connection.
+	if b.cluster.connFaults.refusing() {
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			tcpConn.SetLinger(0)
+		}
+		fmt.Println("msg", "refusing connection", "addr", remoteAddr)
+		return
+	}
+
+	// The TLS handshake is completed first so that the session knows the client certificate
+	if l.TLS != nil {
+		tlsConn := tls.Server(conn, l.TLS)
+		conn = tlsConn
+		conn.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+		if err := tlsConn.Handshake(); err != nil {
+			fmt.Println("msg", "TLS handshake failed", "err", err)
+			return
+		}
+		conn.SetDeadline(time.Time{})
+	}
+	s := newSession(conn, l)
+	// The context of the requests is cancelled once the connection is closed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
+	served := 0
 	for {
 		p := make([]byte, 4)
 		_, err := io.ReadFull(conn, p[:])
@@ -85,33 +255,70 @@ func HandleConnection(conn net.Conn, params *types.Params) {
 			break
 		}
 
-		b := make([]byte, size+4) //+4 since we're going to copy the size into b
-		copy(b, p)
+		buf := make([]byte, size+4) //+4 since we're going to copy the size into buf
+		copy(buf, p)
 
-		if _, err = io.ReadFull(conn, b[4:]); err != nil {
+		if _, err = io.ReadFull(conn, buf[4:]); err != nil {
 			fmt.Println("msg", "failed to read from connection", "err", err)
-			panic(err)
+			break
 		}
 
-		header, d, err := decodeHeader(b)
+		if s.rawSASL {
+			if err := b.authenticateRaw(conn, s, buf[4:]); err != nil {
+				fmt.Println("msg", "closing connection", "err", err)
+				return
+			}
+			continue
+		}
+
+		header, d, err := decodeHeader(buf)
 		if err != nil {
 			fmt.Println("msg", "failed to decode header", "err", err)
-			panic(err)
-		}
-		switch header.APIKey {
-		case protocol.ProduceKey:
-			fmt.Println("Request : Produce Message")
-			handleProduce(conn, d, header, params.Data)
-		case protocol.MetadataKey:
-			fmt.Println("Request : Meta Data")
-			handleMetaData(conn, d, header)
-		case protocol.APIVersionsKey:
-			fmt.Println("Request : Api Version")
-			handleApiVersion(conn, d, header)
+			break
+		}
+		if !s.allowed(header.APIKey) {
+			fmt.Println("msg", "closing connection", "err", errUnauthenticated, "key", header.APIKey)
+			return
+		}
+		h, ok := b.cluster.handlers.lookup(header.APIKey, header.APIVersion)
+		if !ok {
+			a, ok := apis[header.APIKey]
+			if !ok {
+				fmt.Println("msg", "closing connection", "err", "unsupported request", "key", header.APIKey)
+				return
+			}
+			// ApiVersions answers any version with the versions the broker supports
+			if header.APIKey != protocol.APIVersionsKey && (header.APIVersion < a.minVersion || header.APIVersion > a.maxVersion) {
+				fmt.Println("msg", "closing connection", "err", protocol.ErrUnsupportedVersion, "key", header.APIKey, "version", header.APIVersion)
+				return
+			}
+			fmt.Println("Request : " + a.name)
+			h = &builtin{api: a, b: b, conn: conn, s: s}
+		}
+		req := &types.Request{
+			Header: types.RequestHeader{
+				APIKey:        header.APIKey,
+				APIVersion:    header.APIVersion,
+				CorrelationID: header.CorrelationID,
+				ClientID:      header.ClientID,
+			},
+			Body: buf[d.Offset():],
+		}
+		req = req.WithContext(types.ContextWithPrincipal(ctx, s.principal))
+		if err := b.cluster.handlers.wrap(h).ServeKafka(&responseWriter{conn: conn, header: header, faults: b.cluster.connFaults}, req); err != nil {
+			fmt.Println("msg", "closing connection", "err", err)
+			return
+		}
+		served++
+		if b.cluster.connFaults.closeAfter(served) {
+			fmt.Println("msg", "closing connection", "err", "closed by a fault", "requests", served)
+			return
+		}
+
+		select {
+		case <-b.done:
+			return
 		default:
-			fmt.Println("Unsupported request :", header.APIKey)
 		}
 	}
-
-	fmt.Println("Client at " + remoteAddr + " disconnected.")
 }
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"net"
+	"time"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+	"github.com/ninepub/kafka-mock/internal/storage"
+)
+
+func (b *Broker) handleListOffsets(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeListOffsetsRequest(d, header)
+	if err != nil {
+		return err
+	}
+	return handleResponse(conn, b.listOffsets(req, s.faults), header)
+}
+
+func (b *Broker) listOffsets(req *protocol.ListOffsetsRequest, faults *faultRequest) *protocol.ListOffsetsResponse {
+	res := &protocol.ListOffsetsResponse{APIVersion: req.APIVersion}
+	for _, t := range req.Topics {
+		topicResponse := &protocol.ListOffsetsTopicResponse{Topic: t.Topic}
+		res.Topics = append(res.Topics, topicResponse)
+
+		seen := make(map[int32]bool)
+		for _, p := range t.Partitions {
+			pr := &protocol.ListOffsetsPartitionResponse{Partition: p.Partition, Timestamp: -1, Offset: -1, LeaderEpoch: -1}
+			topicResponse.Partitions = append(topicResponse.Partitions, pr)
+
+			log, ok := b.store.Log(t.Topic, p.Partition)
+			err := faults.check(t.Topic, p.Partition)
+			switch {
+			// Versions 1+ may ask for each partition only once
+			case seen[p.Partition] && req.APIVersion >= 1:
+				pr.ErrorCode = protocol.ErrInvalidRequest.Code()
+			case err != nil:
+				pr.ErrorCode = errorCode(err)
+			case !ok:
+				pr.ErrorCode = protocol.ErrUnknownTopicOrPartition.Code()
+			case !b.leads(log):
+				pr.ErrorCode = protocol.ErrNotLeaderForPartition.Code()
+			case p.CurrentLeaderEpoch > 0:
+				pr.ErrorCode = protocol.ErrUnknownLeaderEpoch.Code()
+			case p.Timestamp == protocol.MaxTimestamp && req.APIVersion < 7:
+				pr.ErrorCode = protocol.ErrUnsupportedVersion.Code()
+			case req.APIVersion == 0:
+				pr.OldStyleOffsets = oldStyleOffsets(log, p.Timestamp, p.MaxNumOffsets)
+			default:
+				listOffset(log, p.Timestamp, req.IsolationLevel, pr)
+			}
+			seen[p.Partition] = true
+		}
+	}
+	return res
+}
+
+// listOffset resolves the offset of a timestamp or one of the special timestamps
+func listOffset(log *storage.Log, timestamp int64, isolationLevel int8, pr *protocol.ListOffsetsPartitionResponse) {
+	// read_committed consumers cannot see past the last stable offset
+	limit := log.HighWatermark()
+	if isolationLevel == protocol.ReadCommitted {
+		limit = log.LastStableOffset()
+	}
+
+	switch timestamp {
+	case protocol.LatestTimestamp:
+		pr.Offset = limit
+	case protocol.EarliestTimestamp:
+		pr.Offset = log.LogStartOffset()
+	case protocol.MaxTimestamp:
+		if r, ok := log.MaxTimestamp(limit); ok {
+			pr.Offset = r.Offset
+			pr.Timestamp = millis(r.Timestamp)
+		}
+	default:
+		if r, ok := log.OffsetForTimestamp(time.Unix(0, timestamp*int64(time.Millisecond)), limit); ok {
+			pr.Offset = r.Offset
+			pr.Timestamp = millis(r.Timestamp)
+		}
+	}
+	// The mock never changes leaders, so every record has leader epoch 0
+	if pr.Offset >= 0 {
+		pr.LeaderEpoch = 0
+	}
+}
+
+// oldStyleOffsets answers version 0 requests, which list the offsets of log segments
+// before a timestamp. The mock keeps every partition in a single segment.
+func oldStyleOffsets(log *storage.Log, timestamp int64, maxNumOffsets int32) []int64 {
+	logStartOffset := log.LogStartOffset()
+	highWatermark := log.HighWatermark()
+
+	var offsets []int64
+	switch timestamp {
+	case protocol.LatestTimestamp:
+		if highWatermark > logStartOffset {
+			offsets = append(offsets, highWatermark)
+		}
+		offsets = append(offsets, logStartOffset)
+	case protocol.EarliestTimestamp:
+		offsets = append(offsets, logStartOffset)
+	default:
+		if _, ok := log.OffsetForTimestamp(time.Unix(0, timestamp*int64(time.Millisecond)), highWatermark); ok {
+			offsets = append(offsets, logStartOffset)
+		}
+	}
+	if int(maxNumOffsets) < len(offsets) {
+		offsets = offsets[:maxNumOffsets]
+	}
+	return offsets
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/ninepub/kafka-mock/internal/sasl"
+	"github.com/ninepub/kafka-mock/pkg/types"
+)
+
+// Listener is an endpoint of the broker as advertised to clients
+type Listener struct {
+	Name string
+	Host string
+	Port int32
+	// SASL requires the clients of the listener to authenticate
+	SASL *types.SASL
+	// TLS serves the clients of the listener over TLS. Connections are wrapped once
+	// they are accepted, so that refused connections are reset before any handshake.
+	TLS *tls.Config
+}
+
+// AddListener registers a listener whose connections are then served by HandleConnection
+func (b *Broker) AddListener(l *Listener) error {
+	if l.SASL != nil {
+		for _, m := range l.SASL.Mechanisms {
+			if !sasl.Supported(m) {
+				return fmt.Errorf("unsupported SASL mechanism %s for listener %s", m, l.Name)
+			}
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.listeners[l.Name]; ok {
+		return fmt.Errorf("duplicate listener %s", l.Name)
+	}
+	b.listeners[l.Name] = l
+	return nil
+}
+
+// RemoveListener unregisters the listener with the given name
+func (b *Broker) RemoveListener(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.listeners, name)
+}
+
+// Listener returns the listener with the given name
+func (b *Broker) Listener(name string) (*Listener, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	l, ok := b.listeners[name]
+	return l, ok
+}
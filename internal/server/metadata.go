@@ -0,0 +1,107 @@
+package server
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/ninepub/kafka-mock/internal/group"
+	"github.com/ninepub/kafka-mock/internal/protocol"
+	"github.com/ninepub/kafka-mock/internal/storage"
+)
+
+// defaultClusterID is returned by metadata responses unless Params.ClusterID is set
+const defaultClusterID = "kafka-mock"
+
+func (b *Broker) handleMetaData(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeMetadataRequest(d, header)
+	if err != nil {
+		return err
+	}
+	return handleResponse(conn, b.metadata(req, s.listener, s.faults), header)
+}
+
+// metadata describes the brokers, as advertised by the listener of the request, and
+// the requested topics, creating unknown topics if the request and the broker allow it
+func (b *Broker) metadata(req *protocol.MetadataRequest, l *Listener, faults *faultRequest) *protocol.MetadataResponse {
+	clusterID := b.params.ClusterID
+	if clusterID == "" {
+		clusterID = defaultClusterID
+	}
+	res := &protocol.MetadataResponse{
+		APIVersion: req.APIVersion,
+		ClusterID:  &clusterID,
+		// The first broker acts as the controller
+		ControllerID:                b.cluster.brokers[0].id,
+		ClusterAuthorizedOperations: protocol.UnknownAuthorizedOperations,
+	}
+	for _, broker := range b.cluster.brokers {
+		if bl, ok := broker.Listener(l.Name); ok {
+			res.Brokers = append(res.Brokers, &protocol.Broker{NodeID: broker.id, Host: bl.Host, Port: bl.Port, Rack: nullableString(b.params.Rack)})
+		}
+	}
+
+	topics := req.Topics
+	if topics == nil {
+		topics = b.store.Topics()
+	}
+	for _, topic := range topics {
+		tm := &protocol.TopicMetadata{
+			Topic:                     topic,
+			IsInternal:                topic == group.OffsetsTopic,
+			TopicAuthorizedOperations: protocol.UnknownAuthorizedOperations,
+		}
+		res.TopicMetadata = append(res.TopicMetadata, tm)
+
+		if err := faults.check(topic, -1); err != nil {
+			tm.TopicErrorCode = errorCode(err)
+			continue
+		}
+		partitions := b.store.Partitions(topic)
+		if len(partitions) == 0 {
+			if err := b.autoCreateTopic(topic, req.AllowAutoTopicCreation); err != nil {
+				tm.TopicErrorCode = errorCode(err)
+				continue
+			}
+			partitions = b.store.Partitions(topic)
+		}
+		for _, partition := range partitions {
+			log, ok := b.store.Log(topic, partition)
+			if !ok {
+				continue
+			}
+			tm.PartitionMetadata = append(tm.PartitionMetadata, &protocol.PartitionMetadata{
+				PartitionID: partition,
+				Leader:      log.Leader(),
+				// The mock never changes leaders, so the leader epoch is always 0
+				LeaderEpoch: 0,
+				// Followers never fall behind, so every replica is in sync
+				Replicas:        log.Replicas(),
+				ISR:             log.Replicas(),
+				OfflineReplicas: []int32{},
+			})
+		}
+	}
+	return res
+}
+
+// autoCreateTopic creates a topic requested by a metadata request
+func (b *Broker) autoCreateTopic(topic string, allowed bool) error {
+	if !allowed || b.params.DisableAutoCreateTopics {
+		return protocol.ErrUnknownTopicOrPartition
+	}
+	if !storage.ValidTopicName(topic) {
+		return protocol.ErrInvalidTopicException
+	}
+	partitions := b.defaultPartitions()
+	fmt.Println("msg", "creating topic", "topic", topic, "partitions", partitions)
+	b.store.CreateTopic(topic, b.cluster.assignReplicas(0, partitions, b.cluster.defaultReplicationFactor()), nil)
+	return nil
+}
+
+// defaultPartitions returns the partition count of topics created without one
+func (b *Broker) defaultPartitions() int32 {
+	if b.params.DefaultPartitions <= 0 {
+		return 1
+	}
+	return b.params.DefaultPartitions
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"net"
+	"time"
+
+	"github.com/ninepub/kafka-mock/internal/group"
+	"github.com/ninepub/kafka-mock/internal/protocol"
+)
+
+// maxOffsetMetadataSize is the default of offset.metadata.max.bytes
+const maxOffsetMetadataSize = 4096
+
+func (b *Broker) handleOffsetCommit(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeOffsetCommitRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	res := &protocol.OffsetCommitResponse{APIVersion: header.APIVersion}
+	offsets := make(map[string]map[int32]*group.OffsetAndMetadata)
+	var accepted []*protocol.OffsetCommitPartitionResponse
+	for _, t := range req.Topics {
+		topicResponse := &protocol.OffsetCommitTopicResponse{Topic: t.Topic}
+		res.Topics = append(res.Topics, topicResponse)
+		for _, p := range t.Partitions {
+			pr := &protocol.OffsetCommitPartitionResponse{Partition: p.Partition}
+			topicResponse.Partitions = append(topicResponse.Partitions, pr)
+
+			metadata := stringValue(p.CommittedMetadata)
+			if err := s.faults.check(t.Topic, p.Partition); err != nil {
+				pr.ErrorCode = errorCode(err)
+				continue
+			}
+			if _, ok := b.store.Log(t.Topic, p.Partition); !ok {
+				pr.ErrorCode = protocol.ErrUnknownTopicOrPartition.Code()
+				continue
+			}
+			if len(metadata) > maxOffsetMetadataSize {
+				pr.ErrorCode = protocol.ErrOffsetMetadataTooLarge.Code()
+				continue
+			}
+
+			offset := &group.OffsetAndMetadata{
+				Offset:      p.CommittedOffset,
+				LeaderEpoch: p.CommittedLeaderEpoch,
+				Metadata:    metadata,
+				CommitTime:  now,
+			}
+			if p.CommitTimestamp >= 0 {
+				offset.CommitTime = time.Unix(0, p.CommitTimestamp*int64(time.Millisecond))
+			}
+			if req.RetentionTime >= 0 {
+				offset.ExpireTime = offset.CommitTime.Add(time.Duration(req.RetentionTime) * time.Millisecond)
+			}
+			if offsets[t.Topic] == nil {
+				offsets[t.Topic] = make(map[int32]*group.OffsetAndMetadata)
+			}
+			offsets[t.Topic][p.Partition] = offset
+			accepted = append(accepted, pr)
+		}
+	}
+
+	if len(accepted) > 0 {
+		err = b.groups.CommitOffsets(req.GroupID, req.GenerationID, req.MemberID, stringValue(req.GroupInstanceID), offsets, func() error {
+			return b.writeOffsets(req.GroupID, offsets)
+		})
+		if err != nil {
+			for _, pr := range accepted {
+				pr.ErrorCode = errorCode(err)
+			}
+		}
+	}
+	return handleResponse(conn, res, header)
+}
+
+// writeOffsets appends committed offsets to the offsets topic like Kafka does, so
+// that consumers of the topic can see them
+func (b *Broker) writeOffsets(groupID string, offsets map[string]map[int32]*group.OffsetAndMetadata) error {
+	partition, ok := b.offsetsPartition(groupID)
+	if !ok {
+		return nil
+	}
+	batch := offsetsBatch(groupID, offsets)
+	_, err := b.store.Append(group.OffsetsTopic, partition, &protocol.Records{RecordBatch: batch})
+	return err
+}
+
+// offsetsPartition returns the partition of the offsets topic a group commits to
+func (b *Broker) offsetsPartition(groupID string) (int32, bool) {
+	partitions := b.store.Partitions(group.OffsetsTopic)
+	if len(partitions) == 0 {
+		return 0, false
+	}
+	return group.OffsetsPartition(groupID, int32(len(partitions))), true
+}
+
+// offsetsBatch builds the batch of offset commit records of a group
+func offsetsBatch(groupID string, offsets map[string]map[int32]*group.OffsetAndMetadata) *protocol.RecordBatch {
+	now := time.Now()
+	batch := &protocol.RecordBatch{
+		Version:        2,
+		FirstTimestamp: now,
+		MaxTimestamp:   now,
+		ProducerID:     -1,
+		ProducerEpoch:  -1,
+		FirstSequence:  -1,
+	}
+	for _, topic := range sortedOffsetTopics(offsets) {
+		for _, partition := range sortedOffsetPartitions(offsets[topic]) {
+			batch.Records = append(batch.Records, &protocol.Record{
+				OffsetDelta: int64(len(batch.Records)),
+				Key:         group.OffsetCommitKey(groupID, topic, partition),
+				Value:       group.OffsetCommitValue(offsets[topic][partition]),
+			})
+		}
+	}
+	batch.LastOffsetDelta = int32(len(batch.Records) - 1)
+	return batch
+}
+
+func (b *Broker) handleOffsetFetch(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeOffsetFetchRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	offsets := b.groups.Offsets()
+	topics := req.Topics
+	// A null topics array asks for all offsets of the group
+	if topics == nil {
+		for _, topic := range offsets.Topics(req.GroupID) {
+			topics = append(topics, &protocol.OffsetFetchTopic{Topic: topic, Partitions: offsets.Partitions(req.GroupID, topic)})
+		}
+	}
+
+	res := &protocol.OffsetFetchResponse{APIVersion: header.APIVersion}
+	for _, t := range topics {
+		topicResponse := &protocol.OffsetFetchTopicResponse{Topic: t.Topic}
+		res.Topics = append(res.Topics, topicResponse)
+		for _, partition := range t.Partitions {
+			pr := &protocol.OffsetFetchPartitionResponse{Partition: partition, CommittedOffset: -1, CommittedLeaderEpoch: -1}
+			metadata := ""
+			if offset, ok := offsets.Fetch(req.GroupID, t.Topic, partition); ok {
+				pr.CommittedOffset = offset.Offset
+				pr.CommittedLeaderEpoch = offset.LeaderEpoch
+				metadata = offset.Metadata
+			}
+			pr.Metadata = &metadata
+			if err := s.faults.check(t.Topic, partition); err != nil {
+				pr.ErrorCode = errorCode(err)
+				pr.CommittedOffset = -1
+				pr.CommittedLeaderEpoch = -1
+				pr.Metadata = nil
+			}
+			topicResponse.Partitions = append(topicResponse.Partitions, pr)
+		}
+	}
+	return handleResponse(conn, res, header)
+}
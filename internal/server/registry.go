@@ -0,0 +1,159 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+	"github.com/ninepub/kafka-mock/pkg/types"
+)
+
+// route is a handler registered for a range of versions of an API
+type route struct {
+	key        int16
+	minVersion int16
+	maxVersion int16
+	handler    types.Handler
+}
+
+// registry holds the handlers and middleware registered on the cluster. Its routes
+// take precedence over the built-in APIs.
+type registry struct {
+	mu         sync.RWMutex
+	routes     []route
+	middleware []types.Middleware
+}
+
+func (r *registry) handle(rt route) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, rt)
+}
+
+func (r *registry) use(middleware []types.Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middleware = append(r.middleware, middleware...)
+}
+
+// lookup returns the handler of a version of an API, the latest registered first
+func (r *registry) lookup(key, version int16) (types.Handler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := len(r.routes) - 1; i >= 0; i-- {
+		rt := r.routes[i]
+		if rt.key == key && version >= rt.minVersion && version <= rt.maxVersion {
+			return rt.handler, true
+		}
+	}
+	return nil, false
+}
+
+// wrap applies the middleware to a handler, the first registered is the outermost
+func (r *registry) wrap(h types.Handler) types.Handler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		h = r.middleware[i](h)
+	}
+	return h
+}
+
+// versions adds the versions of the registered APIs to the built-in ones, ordered
+// by key. ApiVersions cannot describe gaps, so each key spans all its versions.
+func (r *registry) versions(builtin []protocol.APIVersion) []protocol.APIVersion {
+	ranges := make(map[int16]protocol.APIVersion)
+	for _, v := range builtin {
+		ranges[v.APIKey] = v
+	}
+	r.mu.RLock()
+	for _, rt := range r.routes {
+		v, ok := ranges[rt.key]
+		if !ok {
+			v = protocol.APIVersion{APIKey: rt.key, MinVersion: rt.minVersion, MaxVersion: rt.maxVersion}
+		}
+		if rt.minVersion < v.MinVersion {
+			v.MinVersion = rt.minVersion
+		}
+		if rt.maxVersion > v.MaxVersion {
+			v.MaxVersion = rt.maxVersion
+		}
+		ranges[rt.key] = v
+	}
+	r.mu.RUnlock()
+
+	versions := make([]protocol.APIVersion, 0, len(ranges))
+	for _, v := range ranges {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].APIKey < versions[j].APIKey })
+	return versions
+}
+
+// Handle registers a handler for the versions from minVersion to maxVersion of an
+// API. It overrides the built-in handler and the handlers registered before it for
+// these versions, which ApiVersions then advertises.
+func (c *Cluster) Handle(key, minVersion, maxVersion int16, h types.Handler) error {
+	if key < 0 || minVersion < 0 || minVersion > maxVersion {
+		return fmt.Errorf("invalid versions %d to %d of API %d", minVersion, maxVersion, key)
+	}
+	if h == nil {
+		return fmt.Errorf("nil handler for API %d", key)
+	}
+	c.handlers.handle(route{key: key, minVersion: minVersion, maxVersion: maxVersion, handler: h})
+	return nil
+}
+
+// Use adds middleware wrapping every handler, built-in or registered
+func (c *Cluster) Use(middleware ...types.Middleware) {
+	c.handlers.use(middleware)
+}
+
+// builtin serves a request with a built-in API of the broker
+type builtin struct {
+	api
+	b    *Broker
+	conn net.Conn
+	s    *session
+}
+
+func (h *builtin) ServeKafka(w types.ResponseWriter, r *types.Request) error {
+	header := &protocol.RequestHeader{
+		APIKey:        r.Header.APIKey,
+		APIVersion:    r.Header.APIVersion,
+		CorrelationID: r.Header.CorrelationID,
+		ClientID:      r.Header.ClientID,
+	}
+	h.s.faults = h.b.cluster.faults.request(header)
+	return h.handle(h.b, &responseConn{Conn: h.conn, w: w}, protocol.NewDecoder(r.Body), header, h.s)
+}
+
+// responseConn sends what the built-in handlers write through the response writer,
+// so that middleware sees their responses
+type responseConn struct {
+	net.Conn
+	w types.ResponseWriter
+}
+
+func (c *responseConn) Write(p []byte) (int, error) {
+	return c.w.Write(p)
+}
+
+// responseWriter writes the responses of a request to its connection, subject to
+// the connection faults
+type responseWriter struct {
+	conn   net.Conn
+	header *protocol.RequestHeader
+	faults *connFaults
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	return w.faults.write(w.conn, p)
+}
+
+func (w *responseWriter) WriteResponse(body []byte) error {
+	raw := protocol.RawBody(body)
+	return handleResponse(w, &raw, w.header)
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+	"github.com/ninepub/kafka-mock/internal/sasl"
+)
+
+// saslMechanisms returns the SASL mechanisms enabled on a listener
+func saslMechanisms(l *Listener) []string {
+	if l.SASL == nil {
+		return []string{}
+	}
+	if len(l.SASL.Mechanisms) == 0 {
+		return sasl.Mechanisms
+	}
+	return l.SASL.Mechanisms
+}
+
+// saslEnabled tells whether a SASL mechanism is enabled on a listener
+func saslEnabled(l *Listener, mechanism string) bool {
+	for _, m := range saslMechanisms(l) {
+		if m == mechanism {
+			return true
+		}
+	}
+	return false
+}
+
+// handleSaslHandshake chooses the mechanism of a client. Like Kafka the connection is
+// closed when the handshake fails.
+func (b *Broker) handleSaslHandshake(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeSaslHandshakeRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	res := &protocol.SaslHandshakeResponse{APIVersion: header.APIVersion, Mechanisms: saslMechanisms(s.listener)}
+	switch {
+	// Clients which are not authenticating cannot start a handshake, like on a
+	// listener without SASL or after the authentication
+	case s.principal != "":
+		res.ErrorCode = protocol.ErrIllegalSaslState.Code()
+		return handleResponse(conn, res, header)
+	case s.mechanism != nil:
+		err = protocol.ErrIllegalSaslState
+	case !saslEnabled(s.listener, req.Mechanism):
+		err = protocol.ErrUnsupportedSaslMechanism
+	default:
+		s.mechanism, err = sasl.New(req.Mechanism, s.listener.SASL.Users)
+	}
+	if err != nil {
+		res.ErrorCode = errorCode(err)
+		handleResponse(conn, res, header)
+		return fmt.Errorf("SASL handshake for mechanism %s failed: %w", req.Mechanism, err)
+	}
+	s.rawSASL = header.APIVersion == 0
+	return handleResponse(conn, res, header)
+}
+
+// handleSaslAuthenticate exchanges a SASL token with a client. Like Kafka the
+// connection is closed when the authentication fails.
+func (b *Broker) handleSaslAuthenticate(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeSaslAuthenticateRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	res := &protocol.SaslAuthenticateResponse{APIVersion: header.APIVersion, AuthBytes: []byte{}}
+	if s.principal != "" {
+		res.ErrorCode = protocol.ErrIllegalSaslState.Code()
+		return handleResponse(conn, res, header)
+	}
+	reply, done, err := s.mechanism.Next(req.AuthBytes)
+	if err != nil {
+		msg := err.Error()
+		res.ErrorCode = errorCode(err)
+		res.ErrorMessage = &msg
+		handleResponse(conn, res, header)
+		return err
+	}
+	res.AuthBytes = reply
+	if done {
+		s.authenticated()
+	}
+	return handleResponse(conn, res, header)
+}
+
+// authenticateRaw exchanges a SASL token sent without a Kafka request header, as
+// clients do after a version 0 handshake
+func (b *Broker) authenticateRaw(conn net.Conn, s *session, token []byte) error {
+	reply, done, err := s.mechanism.Next(token)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 4+len(reply))
+	protocol.Encoding.PutUint32(buf, uint32(len(reply)))
+	copy(buf[4:], reply)
+	if _, err := conn.Write(buf); err != nil {
+		return err
+	}
+	if done {
+		s.authenticated()
+	}
+	return nil
+}
+
+// errUnauthenticated closes the connections of clients sending requests before
+// they are authenticated
+var errUnauthenticated = errors.New("unexpected request before SASL authentication")
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+	"github.com/ninepub/kafka-mock/internal/sasl"
+)
+
+// session is the state of a client connection
+type session struct {
+	// listener is the listener the client connected to
+	listener *Listener
+	// mechanism authenticates the client once a SASL handshake chose it
+	mechanism sasl.Mechanism
+	// rawSASL is set by version 0 handshakes, after which the SASL tokens are sent
+	// without Kafka request headers
+	rawSASL bool
+	// principal is the authenticated user, like User:alice
+	principal string
+	// clientSoftwareName and clientSoftwareVersion identify the client, as sent in
+	// ApiVersions requests from version 3
+	clientSoftwareName    string
+	clientSoftwareVersion string
+	// faults decides the faults injected into the request being served, requests of
+	// a connection are served one at a time
+	faults *faultRequest
+}
+
+// anonymousPrincipal is the principal of clients which do not authenticate
+const anonymousPrincipal = "User:ANONYMOUS"
+
+// newSession starts the session of a new connection. Without SASL the principal of
+// a client is the subject of its TLS certificate, if it presented one.
+func newSession(conn net.Conn, l *Listener) *session {
+	if l.SASL != nil {
+		return &session{listener: l}
+	}
+	s := &session{listener: l, principal: anonymousPrincipal}
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if certs := tlsConn.ConnectionState().PeerCertificates; len(certs) > 0 {
+			s.principal = "User:" + certs[0].Subject.String()
+			fmt.Println("msg", "client authenticated", "principal", s.principal)
+		}
+	}
+	return s
+}
+
+// authenticated ends the SASL authentication of the client
+func (s *session) authenticated() {
+	s.principal = "User:" + s.mechanism.Username()
+	s.mechanism = nil
+	s.rawSASL = false
+	fmt.Println("msg", "client authenticated", "principal", s.principal)
+}
+
+// allowed tells whether a request may be served, only the requests of the SASL
+// handshake are served until the client is authenticated
+func (s *session) allowed(apiKey int16) bool {
+	if s.principal != "" {
+		return true
+	}
+	switch apiKey {
+	case protocol.APIVersionsKey, protocol.SaslHandshakeKey:
+		return true
+	case protocol.SaslAuthenticateKey:
+		return s.mechanism != nil
+	default:
+		return false
+	}
+}
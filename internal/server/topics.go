@@ -0,0 +1,265 @@
+package server
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/ninepub/kafka-mock/internal/group"
+	"github.com/ninepub/kafka-mock/internal/protocol"
+	"github.com/ninepub/kafka-mock/internal/storage"
+)
+
+func (b *Broker) handleCreateTopics(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeCreateTopicsRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	res := &protocol.CreateTopicsResponse{APIVersion: header.APIVersion}
+	counts := make(map[string]int)
+	for _, t := range req.Topics {
+		counts[t.Name]++
+	}
+	for _, t := range req.Topics {
+		result := &protocol.CreatableTopicResult{Name: t.Name}
+		res.Topics = append(res.Topics, result)
+
+		err := newAPIError(protocol.ErrInvalidRequest, "topic '%s' is included more than once in the request", t.Name)
+		if counts[t.Name] == 1 {
+			if err = s.faults.check(t.Name, -1); err == nil {
+				err = b.createTopic(t, req.APIVersion, req.ValidateOnly)
+			}
+		}
+		// Like Kafka a request without timeout does not wait for the topic to be created
+		if err == nil && !req.ValidateOnly && req.Timeout <= 0 {
+			err = protocol.ErrRequestTimedOut
+		}
+		if err != nil {
+			result.ErrorCode = errorCode(err)
+			result.ErrorMessage = nullableString(err.Error())
+		}
+	}
+	return handleResponse(conn, res, header)
+}
+
+// createTopic validates a topic of a CreateTopics request and creates it unless validateOnly is set
+func (b *Broker) createTopic(t *protocol.CreatableTopic, version int16, validateOnly bool) error {
+	if _, ok := b.store.Log(t.Name, 0); ok {
+		return newAPIError(protocol.ErrTopicAlreadyExists, "topic '%s' already exists", t.Name)
+	}
+	if !storage.ValidTopicName(t.Name) {
+		return newAPIError(protocol.ErrInvalidTopicException, "topic name '%s' is illegal", t.Name)
+	}
+
+	partitions := t.NumPartitions
+	var replicas [][]int32
+	if len(t.Assignments) > 0 {
+		if t.NumPartitions != -1 || t.ReplicationFactor != -1 {
+			return newAPIError(protocol.ErrInvalidRequest, "both partitions or replication factor and replica assignments were set")
+		}
+		if err := b.checkAssignments(t.Assignments); err != nil {
+			return err
+		}
+		partitions = int32(len(t.Assignments))
+		replicas = make([][]int32, partitions)
+		for _, a := range t.Assignments {
+			replicas[a.Partition] = a.BrokerIDs
+		}
+	} else {
+		replicationFactor := t.ReplicationFactor
+		// Version 4 lets the broker pick the partition count and replication factor
+		if version >= 4 && partitions == -1 {
+			partitions = b.defaultPartitions()
+		}
+		if version >= 4 && replicationFactor == -1 {
+			replicationFactor = b.cluster.defaultReplicationFactor()
+		}
+		if partitions <= 0 {
+			return newAPIError(protocol.ErrInvalidPartitions, "number of partitions must be larger than 0")
+		}
+		if replicationFactor <= 0 {
+			return newAPIError(protocol.ErrInvalidReplicationFactor, "replication factor must be larger than 0")
+		}
+		if brokers := len(b.cluster.brokers); int(replicationFactor) > brokers {
+			return newAPIError(protocol.ErrInvalidReplicationFactor, "replication factor: %d larger than available brokers: %d", replicationFactor, brokers)
+		}
+		replicas = b.cluster.assignReplicas(0, partitions, replicationFactor)
+	}
+
+	configs := make(map[string]string)
+	for _, c := range t.Configs {
+		if c.Value != nil {
+			configs[c.Name] = *c.Value
+		}
+	}
+	config, err := storage.NewConfig(configs)
+	if err != nil {
+		return err
+	}
+
+	if validateOnly {
+		return nil
+	}
+	if !b.store.CreateTopic(t.Name, replicas, config) {
+		return newAPIError(protocol.ErrTopicAlreadyExists, "topic '%s' already exists", t.Name)
+	}
+	fmt.Println("msg", "created topic", "topic", t.Name, "partitions", partitions)
+	return nil
+}
+
+// checkAssignments validates the replica assignments of a topic to create
+func (b *Broker) checkAssignments(assignments []*protocol.ReplicaAssignment) error {
+	seen := make(map[int32]bool)
+	for _, a := range assignments {
+		if a.Partition < 0 || int(a.Partition) >= len(assignments) || seen[a.Partition] {
+			return newAPIError(protocol.ErrInvalidReplicaAssignment, "partitions should be consecutive and start with 0")
+		}
+		seen[a.Partition] = true
+
+		if len(a.BrokerIDs) == 0 || len(a.BrokerIDs) != len(assignments[0].BrokerIDs) {
+			return newAPIError(protocol.ErrInvalidReplicaAssignment, "all partitions should have the same number of replicas")
+		}
+		if err := b.checkReplicas(a.Partition, a.BrokerIDs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkReplicas validates that the replicas of a partition are distinct known brokers
+func (b *Broker) checkReplicas(partition int32, brokerIDs []int32) error {
+	brokers := make(map[int32]bool)
+	for _, id := range b.cluster.brokerIDs() {
+		brokers[id] = true
+	}
+	replicas := make(map[int32]bool)
+	for _, id := range brokerIDs {
+		if replicas[id] {
+			return newAPIError(protocol.ErrInvalidReplicaAssignment, "duplicate replica %d for partition %d", id, partition)
+		}
+		if !brokers[id] {
+			return newAPIError(protocol.ErrInvalidReplicaAssignment, "unknown broker %d in the assignment of partition %d", id, partition)
+		}
+		replicas[id] = true
+	}
+	return nil
+}
+
+func (b *Broker) handleCreatePartitions(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeCreatePartitionsRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	res := &protocol.CreatePartitionsResponse{APIVersion: header.APIVersion}
+	counts := make(map[string]int)
+	for _, t := range req.Topics {
+		counts[t.Name]++
+	}
+	for _, t := range req.Topics {
+		result := &protocol.CreatePartitionsTopicResult{Name: t.Name}
+		res.Results = append(res.Results, result)
+
+		err := newAPIError(protocol.ErrInvalidRequest, "topic '%s' is included more than once in the request", t.Name)
+		if counts[t.Name] == 1 {
+			if err = s.faults.check(t.Name, -1); err == nil {
+				err = b.createPartitions(t, req.ValidateOnly)
+			}
+		}
+		if err == nil && !req.ValidateOnly && req.Timeout <= 0 {
+			err = protocol.ErrRequestTimedOut
+		}
+		if err != nil {
+			result.ErrorCode = errorCode(err)
+			result.ErrorMessage = nullableString(err.Error())
+		}
+	}
+	return handleResponse(conn, res, header)
+}
+
+// createPartitions validates a topic of a CreatePartitions request and adds the new
+// partitions unless validateOnly is set
+func (b *Broker) createPartitions(t *protocol.CreatePartitionsTopic, validateOnly bool) error {
+	existing := int32(len(b.store.Partitions(t.Name)))
+	first, ok := b.store.Log(t.Name, 0)
+	if existing == 0 || !ok {
+		return newAPIError(protocol.ErrUnknownTopicOrPartition, "the topic '%s' does not exist", t.Name)
+	}
+	if t.Count < existing {
+		return newAPIError(protocol.ErrInvalidPartitions, "topic currently has %d partitions, which is higher than the requested %d", existing, t.Count)
+	}
+	if t.Count == existing {
+		return newAPIError(protocol.ErrInvalidPartitions, "topic already has %d partitions", existing)
+	}
+
+	// The new partitions keep the replication factor of the topic
+	replicationFactor := len(first.Replicas())
+	replicas := b.cluster.assignReplicas(existing, t.Count, int16(replicationFactor))
+	if t.Assignments != nil {
+		if int32(len(t.Assignments)) != t.Count-existing {
+			return newAPIError(protocol.ErrInvalidReplicaAssignment, "increasing the number of partitions by %d but %d assignments provided", t.Count-existing, len(t.Assignments))
+		}
+		for i, brokerIDs := range t.Assignments {
+			partition := existing + int32(i)
+			if len(brokerIDs) != replicationFactor {
+				return newAPIError(protocol.ErrInvalidReplicaAssignment, "inconsistent replication factor between partitions, partition 0 has %d while partition %d has %d replicas", replicationFactor, partition, len(brokerIDs))
+			}
+			if err := b.checkReplicas(partition, brokerIDs); err != nil {
+				return err
+			}
+			replicas[partition] = brokerIDs
+		}
+	}
+
+	if validateOnly {
+		return nil
+	}
+	b.store.CreateTopic(t.Name, replicas, nil)
+	fmt.Println("msg", "created partitions", "topic", t.Name, "partitions", t.Count)
+	return nil
+}
+
+func (b *Broker) handleDeleteTopics(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeDeleteTopicsRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	res := &protocol.DeleteTopicsResponse{APIVersion: header.APIVersion}
+	counts := make(map[string]int)
+	for _, name := range req.TopicNames {
+		counts[name]++
+	}
+	for _, name := range req.TopicNames {
+		result := &protocol.DeletableTopicResult{Name: name}
+		res.Responses = append(res.Responses, result)
+
+		var err error = protocol.ErrInvalidRequest
+		if counts[name] == 1 {
+			if err = s.faults.check(name, -1); err == nil {
+				err = b.deleteTopic(name)
+			}
+		}
+		if err == nil && req.Timeout <= 0 {
+			err = protocol.ErrRequestTimedOut
+		}
+		if err != nil {
+			result.ErrorCode = errorCode(err)
+		}
+	}
+	return handleResponse(conn, res, header)
+}
+
+// deleteTopic removes a topic along with the offsets committed for it
+func (b *Broker) deleteTopic(name string) error {
+	// The offsets topic backs the group coordinator, so the mock does not let it go
+	if name == group.OffsetsTopic {
+		return protocol.ErrInvalidRequest
+	}
+	if !b.store.DeleteTopic(name) {
+		return protocol.ErrUnknownTopicOrPartition
+	}
+	b.groups.Offsets().DeleteTopic(name)
+	fmt.Println("msg", "deleted topic", "topic", name)
+	return nil
+}
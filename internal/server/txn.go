@@ -0,0 +1,215 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/ninepub/kafka-mock/internal/group"
+	"github.com/ninepub/kafka-mock/internal/protocol"
+	"github.com/ninepub/kafka-mock/internal/txn"
+)
+
+func (b *Broker) handleInitProducerID(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeInitProducerIDRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	res := &protocol.InitProducerIDResponse{APIVersion: header.APIVersion, ProducerID: -1, ProducerEpoch: -1}
+	if err := s.faults.check("", -1); err != nil {
+		res.ErrorCode = errorCode(err)
+		return handleResponse(conn, res, header)
+	}
+	id, epoch, err := b.txns.InitProducerID(req.TransactionalID, req.TransactionTimeout)
+	if err != nil {
+		res.ErrorCode = errorCode(err)
+	} else {
+		res.ProducerID = id
+		res.ProducerEpoch = epoch
+	}
+	return handleResponse(conn, res, header)
+}
+
+func (b *Broker) handleAddPartitionsToTxn(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeAddPartitionsToTxnRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	res := &protocol.AddPartitionsToTxnResponse{APIVersion: header.APIVersion}
+	var results []*protocol.AddPartitionsToTxnPartitionResult
+	failed := false
+	for _, topic := range sortedTxnTopics(req.Topics) {
+		tr := &protocol.AddPartitionsToTxnTopicResult{Name: topic}
+		res.Results = append(res.Results, tr)
+		for _, partition := range req.Topics[topic] {
+			pr := &protocol.AddPartitionsToTxnPartitionResult{Partition: partition}
+			tr.Results = append(tr.Results, pr)
+			if err := s.faults.check(topic, partition); err != nil {
+				pr.ErrorCode = errorCode(err)
+				failed = true
+				continue
+			}
+			if _, ok := b.store.Log(topic, partition); !ok {
+				pr.ErrorCode = protocol.ErrUnknownTopicOrPartition.Code()
+				failed = true
+				continue
+			}
+			results = append(results, pr)
+		}
+	}
+
+	// Like Kafka no partition is added when any of them fails
+	if failed {
+		err = protocol.ErrOperationNotAttempted
+	} else {
+		err = b.txns.AddPartitions(req.TransactionalID, req.ProducerID, req.ProducerEpoch, req.Topics)
+	}
+	if err != nil {
+		for _, pr := range results {
+			pr.ErrorCode = errorCode(err)
+		}
+	}
+	return handleResponse(conn, res, header)
+}
+
+func (b *Broker) handleAddOffsetsToTxn(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeAddOffsetsToTxnRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	res := &protocol.AddOffsetsToTxnResponse{APIVersion: header.APIVersion}
+	if err := s.faults.check("", -1); err != nil {
+		res.ErrorCode = errorCode(err)
+		return handleResponse(conn, res, header)
+	}
+	partition, ok := b.offsetsPartition(req.GroupID)
+	if !ok {
+		err = protocol.ErrCoordinatorNotAvailable
+	} else {
+		// The offsets committed within the transaction are written to the partition
+		// of the offsets topic of the group, which joins the transaction
+		err = b.txns.AddPartitions(req.TransactionalID, req.ProducerID, req.ProducerEpoch, map[string][]int32{
+			group.OffsetsTopic: {partition},
+		})
+	}
+	if err != nil {
+		res.ErrorCode = errorCode(err)
+	}
+	return handleResponse(conn, res, header)
+}
+
+func (b *Broker) handleEndTxn(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeEndTxnRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	res := &protocol.EndTxnResponse{APIVersion: header.APIVersion}
+	if err := s.faults.check("", -1); err != nil {
+		res.ErrorCode = errorCode(err)
+		return handleResponse(conn, res, header)
+	}
+	if err := b.txns.EndTxn(req.TransactionalID, req.ProducerID, req.ProducerEpoch, req.Committed); err != nil {
+		res.ErrorCode = errorCode(err)
+	}
+	return handleResponse(conn, res, header)
+}
+
+func (b *Broker) handleTxnOffsetCommit(conn net.Conn, d *protocol.ByteDecoder, header *protocol.RequestHeader, s *session) error {
+	req, err := decodeTxnOffsetCommitRequest(d, header)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	res := &protocol.TxnOffsetCommitResponse{APIVersion: header.APIVersion}
+	offsets := make(map[string]map[int32]*group.OffsetAndMetadata)
+	var accepted []*protocol.TxnOffsetCommitPartitionResponse
+	for _, t := range req.Topics {
+		topicResponse := &protocol.TxnOffsetCommitTopicResponse{Name: t.Name}
+		res.Topics = append(res.Topics, topicResponse)
+		for _, p := range t.Partitions {
+			pr := &protocol.TxnOffsetCommitPartitionResponse{Partition: p.Partition}
+			topicResponse.Partitions = append(topicResponse.Partitions, pr)
+
+			metadata := stringValue(p.CommittedMetadata)
+			if err := s.faults.check(t.Name, p.Partition); err != nil {
+				pr.ErrorCode = errorCode(err)
+				continue
+			}
+			if _, ok := b.store.Log(t.Name, p.Partition); !ok {
+				pr.ErrorCode = protocol.ErrUnknownTopicOrPartition.Code()
+				continue
+			}
+			if len(metadata) > maxOffsetMetadataSize {
+				pr.ErrorCode = protocol.ErrOffsetMetadataTooLarge.Code()
+				continue
+			}
+			if offsets[t.Name] == nil {
+				offsets[t.Name] = make(map[int32]*group.OffsetAndMetadata)
+			}
+			offsets[t.Name][p.Partition] = &group.OffsetAndMetadata{
+				Offset:      p.CommittedOffset,
+				LeaderEpoch: p.CommittedLeaderEpoch,
+				Metadata:    metadata,
+				CommitTime:  now,
+			}
+			accepted = append(accepted, pr)
+		}
+	}
+
+	if len(accepted) > 0 {
+		err = b.txnOffsetCommit(req, offsets)
+		if err != nil {
+			for _, pr := range accepted {
+				pr.ErrorCode = errorCode(err)
+			}
+		}
+	}
+	return handleResponse(conn, res, header)
+}
+
+// txnOffsetCommit writes the offsets committed within a transaction to the offsets
+// topic. They are only applied once the markers of the transaction are written.
+func (b *Broker) txnOffsetCommit(req *protocol.TxnOffsetCommitRequest, offsets map[string]map[int32]*group.OffsetAndMetadata) error {
+	partition, ok := b.offsetsPartition(req.GroupID)
+	if !ok {
+		return protocol.ErrCoordinatorNotAvailable
+	}
+	err := b.txns.Check(req.TransactionalID, req.ProducerID, req.ProducerEpoch, group.OffsetsTopic, partition)
+	if err != nil {
+		return err
+	}
+
+	batch := offsetsBatch(req.GroupID, offsets)
+	batch.ProducerID = req.ProducerID
+	batch.ProducerEpoch = req.ProducerEpoch
+	batch.IsTransactional = true
+	if _, err := b.store.AppendFromCoordinator(group.OffsetsTopic, partition, &protocol.Records{RecordBatch: batch}); err != nil {
+		return err
+	}
+	for topic, partitions := range offsets {
+		for p, offset := range partitions {
+			b.groups.Offsets().CommitPending(req.ProducerID, req.GroupID, topic, p, offset)
+		}
+	}
+	return nil
+}
+
+// writeMarkers writes the COMMIT or ABORT markers of a transaction to its partitions
+// and applies or drops the offsets committed within it
+func (c *Cluster) writeMarkers(m *txn.Markers) {
+	for _, topic := range sortedTxnTopics(m.Partitions) {
+		for _, partition := range m.Partitions[topic] {
+			if _, err := c.store.AppendMarker(topic, partition, m.ProducerID, m.ProducerEpoch, m.Commit); err != nil {
+				fmt.Println("msg", "failed to write transaction marker", "topic", topic, "partition", partition, "err", err)
+			}
+		}
+	}
+	if _, ok := m.Partitions[group.OffsetsTopic]; ok {
+		c.groups.Offsets().CompleteTransaction(m.ProducerID, m.Commit)
+	}
+}
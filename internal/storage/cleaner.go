@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+)
+
+// clean applies the cleanup policies of the topic. Logs are compacted again only
+// once records were appended to them.
+func (l *Log) clean(now time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.deleteRetained(now)
+	if !l.config.Compact || l.cleanedOffset == l.nextOffset {
+		return nil
+	}
+	if err := l.compact(); err != nil {
+		return err
+	}
+	l.cleanedOffset = l.nextOffset
+	return nil
+}
+
+// deleteRetained removes the oldest entries exceeding retention.ms or retention.bytes
+// and moves the log start offset past them. Every entry acts as a log segment.
+func (l *Log) deleteRetained(now time.Time) {
+	if !l.config.Delete {
+		return
+	}
+
+	size := int64(0)
+	for _, entry := range l.entries {
+		size += int64(len(entry.encoded))
+	}
+	deleted := 0
+	for i, entry := range l.entries {
+		// Messages without a timestamp expire by their append time like segments by their mtime
+		ts := entry.MaxTimestamp()
+		if ts.IsZero() {
+			ts = entry.appended
+		}
+		expired := l.config.RetentionMs >= 0 && now.Sub(ts) > time.Duration(l.config.RetentionMs)*time.Millisecond
+		// Like the active segment, the last entry is never deleted because of its size
+		tooLarge := l.config.RetentionBytes >= 0 && size > l.config.RetentionBytes && i < len(l.entries)-1
+		if !expired && !tooLarge {
+			break
+		}
+		size -= int64(len(entry.encoded))
+		deleted++
+	}
+	if deleted == 0 {
+		return
+	}
+
+	l.entries = append([]*Entry(nil), l.entries[deleted:]...)
+	if len(l.entries) > 0 {
+		l.setLogStartOffset(l.entries[0].BaseOffset)
+	} else {
+		l.setLogStartOffset(l.nextOffset)
+	}
+}
+
+// DeleteRecords moves the log start offset to offset, -1 stands for the high watermark.
+// Entries holding only deleted records are removed. It returns the new log start offset.
+func (l *Log) DeleteRecords(offset int64) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.config.Compact && !l.config.Delete {
+		return -1, protocol.ErrPolicyViolation
+	}
+	if offset == -1 {
+		offset = l.nextOffset
+	}
+	if offset < 0 || offset > l.nextOffset {
+		return -1, protocol.ErrOffsetOutOfRange
+	}
+
+	l.setLogStartOffset(offset)
+	l.entries = append([]*Entry(nil), l.entries[l.search(l.logStartOffset):]...)
+	return l.logStartOffset, nil
+}
+
+func (l *Log) setLogStartOffset(offset int64) {
+	if offset > l.logStartOffset {
+		l.logStartOffset = offset
+	}
+	// Aborted transactions are forgotten once their marker is deleted
+	aborted := l.aborted[:0]
+	for _, txn := range l.aborted {
+		if txn.lastOffset >= l.logStartOffset {
+			aborted = append(aborted, txn)
+		}
+	}
+	l.aborted = aborted
+}
+
+// compact keeps only the latest record of every key. Like Kafka the last entry,
+// which stands for the active segment, and entries of ongoing transactions are never
+// compacted, records of aborted transactions are removed and offsets are kept.
+func (l *Log) compact() error {
+	lso := l.lastStableOffset()
+	latest := make(map[string]int64)
+	for _, entry := range l.entries {
+		if entry.BaseOffset >= lso {
+			break
+		}
+		if entry.Batch != nil && l.isAborted(entry.Batch, entry.BaseOffset) {
+			continue
+		}
+		for _, r := range entry.Records(l.Topic, l.Partition) {
+			latest[string(r.Key)] = r.Offset
+		}
+	}
+
+	var entries []*Entry
+	for i, entry := range l.entries {
+		if i == len(l.entries)-1 || entry.BaseOffset >= lso {
+			entries = append(entries, l.entries[i:]...)
+			break
+		}
+		compacted, err := entry.compact(latest)
+		if err != nil {
+			return err
+		}
+		if compacted != nil {
+			entries = append(entries, compacted)
+		}
+	}
+	l.entries = entries
+	return nil
+}
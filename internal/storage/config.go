@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+)
+
+// Topic configs applied by the mock
+const (
+	RetentionMsConfig          = "retention.ms"
+	RetentionBytesConfig       = "retention.bytes"
+	CleanupPolicyConfig        = "cleanup.policy"
+	CompressionTypeConfig      = "compression.type"
+	MessageTimestampTypeConfig = "message.timestamp.type"
+)
+
+// defaultRetentionMs is the default of log.retention.hours, 7 days
+const defaultRetentionMs = 7 * 24 * 60 * 60 * 1000
+
+// Config holds the configs of a topic
+type Config struct {
+	// RetentionMs and RetentionBytes limit how long and how much data the logs
+	// of a topic keep, -1 means no limit
+	RetentionMs    int64
+	RetentionBytes int64
+	// Delete and Compact are the cleanup policies of the topic
+	Delete  bool
+	Compact bool
+	// Codec recompresses the appended record batches unless KeepCodec is set,
+	// which is the producer compression type
+	Codec     protocol.CompressionCodec
+	KeepCodec bool
+	// LogAppendTime replaces the timestamps of appended records with the append time
+	LogAppendTime bool
+
+	// Configs holds the configs set for the topic, including those the mock does not apply
+	Configs map[string]string
+}
+
+// DefaultConfig returns the config of topics without any config set
+func DefaultConfig() *Config {
+	return &Config{
+		RetentionMs:    defaultRetentionMs,
+		RetentionBytes: -1,
+		Delete:         true,
+		KeepCodec:      true,
+		Configs:        map[string]string{},
+	}
+}
+
+// NewConfig parses the configs of a topic. Configs the mock does not apply are kept as they are.
+func NewConfig(configs map[string]string) (*Config, error) {
+	c := DefaultConfig()
+	for name, value := range configs {
+		if err := c.set(name, value); err != nil {
+			return nil, err
+		}
+		c.Configs[name] = value
+	}
+	return c, nil
+}
+
+func (c *Config) set(name, value string) (err error) {
+	switch name {
+	case RetentionMsConfig:
+		c.RetentionMs, err = parseLimit(name, value)
+	case RetentionBytesConfig:
+		c.RetentionBytes, err = parseLimit(name, value)
+	case CleanupPolicyConfig:
+		c.Delete, c.Compact = false, false
+		for _, policy := range strings.Split(value, ",") {
+			switch strings.TrimSpace(policy) {
+			case "delete":
+				c.Delete = true
+			case "compact":
+				c.Compact = true
+			default:
+				return configError(name, value)
+			}
+		}
+	case CompressionTypeConfig:
+		c.KeepCodec = false
+		switch value {
+		case "producer":
+			c.KeepCodec = true
+		case "uncompressed":
+			c.Codec = protocol.CompressionNone
+		case "gzip":
+			c.Codec = protocol.CompressionGZIP
+		case "snappy":
+			c.Codec = protocol.CompressionSnappy
+		case "lz4":
+			c.Codec = protocol.CompressionLZ4
+		case "zstd":
+			c.Codec = protocol.CompressionZSTD
+		default:
+			return configError(name, value)
+		}
+	case MessageTimestampTypeConfig:
+		switch value {
+		case "CreateTime":
+			c.LogAppendTime = false
+		case "LogAppendTime":
+			c.LogAppendTime = true
+		default:
+			return configError(name, value)
+		}
+	}
+	return err
+}
+
+func parseLimit(name, value string) (int64, error) {
+	v, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || v < -1 {
+		return 0, configError(name, value)
+	}
+	return v, nil
+}
+
+// ConfigError is returned for invalid topic configs, it wraps ErrInvalidConfig
+type ConfigError struct {
+	Name  string
+	Value string
+}
+
+func (e ConfigError) Error() string {
+	return fmt.Sprintf("invalid value %q for topic config %s", e.Value, e.Name)
+}
+
+func (e ConfigError) Unwrap() error {
+	return protocol.ErrInvalidConfig
+}
+
+func configError(name, value string) error {
+	return ConfigError{Name: name, Value: value}
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+	"github.com/ninepub/kafka-mock/pkg/types"
+)
+
+// Entry is a single record batch or legacy message stored in a partition log
+type Entry struct {
+	BaseOffset int64
+	LastOffset int64
+	Batch      *protocol.RecordBatch
+	Message    *protocol.MessageBlock
+
+	encoded []byte
+	// appended is when the entry was added to the log
+	appended time.Time
+}
+
+func newBatchEntry(batch *protocol.RecordBatch, baseOffset int64) (*Entry, error) {
+	batch.FirstOffset = baseOffset
+	encoded, err := protocol.Encode(batch)
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{
+		BaseOffset: baseOffset,
+		LastOffset: batch.LastOffset(),
+		Batch:      batch,
+		encoded:    encoded,
+	}, nil
+}
+
+func newMessageEntry(msg *protocol.Message, offset int64) (*Entry, error) {
+	block := &protocol.MessageBlock{Offset: offset, Msg: msg}
+	encoded, err := protocol.Encode(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{
+		BaseOffset: offset,
+		LastOffset: offset,
+		Message:    block,
+		encoded:    encoded,
+	}, nil
+}
+
+// MaxTimestamp returns the largest timestamp of the records in the entry
+func (e *Entry) MaxTimestamp() time.Time {
+	if e.Batch != nil {
+		return e.Batch.MaxTimestamp
+	}
+	return e.Message.Msg.Timestamp
+}
+
+// Records returns the records held by the entry along with their offsets and metadata
+func (e *Entry) Records(topic string, partition int32) []*types.Record {
+	if e.Batch == nil {
+		return []*types.Record{{
+			Topic:     topic,
+			Partition: partition,
+			Offset:    e.BaseOffset,
+			Key:       e.Message.Msg.Key,
+			Value:     e.Message.Msg.Value,
+			Timestamp: e.Message.Msg.Timestamp,
+		}}
+	}
+	if e.Batch.Control {
+		return nil
+	}
+	records := make([]*types.Record, 0, len(e.Batch.Records))
+	for _, r := range e.Batch.Records {
+		record := &types.Record{
+			Topic:     topic,
+			Partition: partition,
+			Offset:    e.BaseOffset + r.OffsetDelta,
+			Key:       r.Key,
+			Value:     r.Value,
+			Timestamp: e.Batch.FirstTimestamp.Add(r.TimestampDelta),
+		}
+		if e.Batch.LogAppendTime {
+			record.Timestamp = e.Batch.MaxTimestamp
+		}
+		for _, h := range r.Headers {
+			record.Headers = append(record.Headers, &types.RecordHeader{Key: h.Key, Value: h.Value})
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// Encode returns the entry in the requested message format, down converting
+// record batches for clients which can only read older magic versions
+func (e *Entry) Encode(magic int8) ([]byte, error) {
+	if e.Batch != nil {
+		if magic >= 2 {
+			return e.encoded, nil
+		}
+		return e.downConvertBatch(magic)
+	}
+	if magic >= e.Message.Msg.Version {
+		return e.encoded, nil
+	}
+	msg := *e.Message.Msg
+	msg.Version = magic
+	return protocol.Encode(&protocol.MessageBlock{Offset: e.BaseOffset, Msg: &msg})
+}
+
+func (e *Entry) downConvertBatch(magic int8) ([]byte, error) {
+	// Control batches are never exposed to clients reading the old formats
+	if e.Batch.Control {
+		return nil, nil
+	}
+	set := &protocol.MessageSet{}
+	for _, r := range e.Batch.Records {
+		timestamp := e.Batch.FirstTimestamp.Add(r.TimestampDelta)
+		if e.Batch.LogAppendTime {
+			timestamp = e.Batch.MaxTimestamp
+		}
+		set.Messages = append(set.Messages, &protocol.MessageBlock{
+			Offset: e.BaseOffset + r.OffsetDelta,
+			Msg: &protocol.Message{
+				Version:       magic,
+				Key:           r.Key,
+				Value:         r.Value,
+				Timestamp:     timestamp,
+				LogAppendTime: e.Batch.LogAppendTime,
+			},
+		})
+	}
+	return protocol.Encode(set)
+}
+
+// compact returns the entry without the records which are not the latest of their
+// key, or nil if no record is left. Control batches are always kept.
+func (e *Entry) compact(latest map[string]int64) (*Entry, error) {
+	if e.Batch == nil {
+		if offset, ok := latest[string(e.Message.Msg.Key)]; !ok || offset != e.BaseOffset {
+			return nil, nil
+		}
+		return e, nil
+	}
+	if e.Batch.Control {
+		return e, nil
+	}
+
+	var records []*protocol.Record
+	for _, r := range e.Batch.Records {
+		if offset, ok := latest[string(r.Key)]; ok && offset == e.BaseOffset+r.OffsetDelta {
+			records = append(records, r)
+		}
+	}
+	if len(records) == len(e.Batch.Records) {
+		return e, nil
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	// The batch keeps its offset range so that the remaining records keep their offsets
+	batch := &protocol.RecordBatch{
+		PartitionLeaderEpoch: e.Batch.PartitionLeaderEpoch,
+		Version:              e.Batch.Version,
+		Codec:                e.Batch.Codec,
+		CompressionLevel:     protocol.CompressionLevelDefault,
+		LogAppendTime:        e.Batch.LogAppendTime,
+		LastOffsetDelta:      e.Batch.LastOffsetDelta,
+		FirstTimestamp:       e.Batch.FirstTimestamp,
+		MaxTimestamp:         e.Batch.MaxTimestamp,
+		ProducerID:           e.Batch.ProducerID,
+		ProducerEpoch:        e.Batch.ProducerEpoch,
+		FirstSequence:        e.Batch.FirstSequence,
+		Records:              records,
+		IsTransactional:      e.Batch.IsTransactional,
+	}
+	compacted, err := newBatchEntry(batch, e.BaseOffset)
+	if err != nil {
+		return nil, err
+	}
+	compacted.appended = e.appended
+	return compacted, nil
+}
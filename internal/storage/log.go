@@ -0,0 +1,326 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+	"github.com/ninepub/kafka-mock/pkg/types"
+)
+
+// Log is the append only log of a single topic partition
+type Log struct {
+	Topic     string
+	Partition int32
+
+	config *Config
+	// replicas are the brokers holding the partition, the first one leads it
+	replicas []int32
+
+	mu             sync.RWMutex
+	entries        []*Entry
+	logStartOffset int64
+	nextOffset     int64
+	producers      map[int64]*producerState
+	// aborted indexes the aborted transactions by their first offset
+	aborted []abortedTxn
+	// cleanedOffset is the next offset of the log when it was last compacted
+	cleanedOffset int64
+}
+
+// AppendInfo describes the records added to a log by a single append
+type AppendInfo struct {
+	FirstOffset    int64
+	LastOffset     int64
+	LogStartOffset int64
+	// LogAppendTime is set when the topic replaces record timestamps with the append time
+	LogAppendTime time.Time
+	Records       []*types.Record
+	// Duplicate is set when the batch had already been appended by an idempotent
+	// producer, the offsets are then those of the earlier append
+	Duplicate bool
+}
+
+func newLog(topic string, partition int32, config *Config, replicas []int32) *Log {
+	return &Log{
+		Topic:     topic,
+		Partition: partition,
+		config:    config,
+		replicas:  replicas,
+		producers: make(map[int64]*producerState),
+	}
+}
+
+// Config returns the configs of the topic of the log
+func (l *Log) Config() *Config {
+	return l.config
+}
+
+// Replicas returns the IDs of the brokers holding the partition
+func (l *Log) Replicas() []int32 {
+	return l.replicas
+}
+
+// Leader returns the ID of the broker leading the partition
+func (l *Log) Leader() int32 {
+	return l.replicas[0]
+}
+
+// Append assigns offsets to the records of a client and adds them to the log
+func (l *Log) Append(records *protocol.Records) (*AppendInfo, error) {
+	return l.append(records, fromClient)
+}
+
+func (l *Log) append(records *protocol.Records, origin appendOrigin) (*AppendInfo, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	producer, duplicate, err := l.checkProducer(records.RecordBatch, origin)
+	if err != nil {
+		return nil, err
+	}
+	if duplicate != nil {
+		return &AppendInfo{
+			FirstOffset:    duplicate.firstOffset,
+			LastOffset:     duplicate.lastOffset,
+			LogStartOffset: l.logStartOffset,
+			Duplicate:      true,
+		}, nil
+	}
+
+	now := time.Now()
+	entries, err := l.newEntries(records, now)
+	if err != nil {
+		return nil, err
+	}
+	if producer != nil {
+		producer.update(records.RecordBatch, entries[0].BaseOffset, entries[0].LastOffset)
+		l.producers[records.RecordBatch.ProducerID] = producer
+	}
+
+	info := &AppendInfo{
+		FirstOffset: l.nextOffset,
+		LastOffset:  l.nextOffset - 1,
+	}
+	if l.config.LogAppendTime {
+		info.LogAppendTime = now
+	}
+	for _, entry := range entries {
+		entry.appended = now
+		l.entries = append(l.entries, entry)
+		l.nextOffset = entry.LastOffset + 1
+		info.LastOffset = entry.LastOffset
+		info.Records = append(info.Records, entry.Records(l.Topic, l.Partition)...)
+	}
+	info.LogStartOffset = l.logStartOffset
+	return info, nil
+}
+
+func (l *Log) newEntries(records *protocol.Records, now time.Time) ([]*Entry, error) {
+	if batch := records.RecordBatch; batch != nil {
+		if batch.PartialTrailingRecord {
+			return nil, protocol.ErrCorruptMessage
+		}
+		if l.config.Compact && !batch.Control {
+			for _, r := range batch.Records {
+				if r.Key == nil {
+					return nil, protocol.ErrCorruptMessage
+				}
+			}
+		}
+		// Like Kafka the max timestamp is computed again, clients may leave it unset
+		if l.config.LogAppendTime {
+			batch.LogAppendTime = true
+			batch.MaxTimestamp = now
+		} else if !batch.LogAppendTime && !batch.FirstTimestamp.IsZero() {
+			batch.MaxTimestamp = batch.FirstTimestamp
+			for _, r := range batch.Records {
+				if ts := batch.FirstTimestamp.Add(r.TimestampDelta); ts.After(batch.MaxTimestamp) {
+					batch.MaxTimestamp = ts
+				}
+			}
+		}
+		// Decoded batches are always compressed again when they are stored
+		if !l.config.KeepCodec {
+			batch.Codec = l.config.Codec
+		}
+		batch.CompressionLevel = protocol.CompressionLevelDefault
+		entry, err := newBatchEntry(batch, l.nextOffset)
+		if err != nil {
+			return nil, err
+		}
+		return []*Entry{entry}, nil
+	}
+
+	if records.MsgSet == nil {
+		return nil, nil
+	}
+	// Legacy message sets are flattened so that every stored message has its own offset
+	var entries []*Entry
+	offset := l.nextOffset
+	for _, block := range records.MsgSet.Messages {
+		for _, inner := range block.Messages() {
+			if l.config.Compact && inner.Msg.Key == nil {
+				return nil, protocol.ErrCorruptMessage
+			}
+			msg := &protocol.Message{
+				Version:       inner.Msg.Version,
+				Key:           inner.Msg.Key,
+				Value:         inner.Msg.Value,
+				Timestamp:     inner.Msg.Timestamp,
+				LogAppendTime: block.Msg.LogAppendTime,
+			}
+			if block.Msg.LogAppendTime {
+				msg.Timestamp = block.Msg.Timestamp
+			}
+			if l.config.LogAppendTime && msg.Version >= 1 {
+				msg.LogAppendTime = true
+				msg.Timestamp = now
+			}
+			entry, err := newMessageEntry(msg, offset)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+			offset++
+		}
+	}
+	return entries, nil
+}
+
+// HighWatermark returns the offset of the next record to be appended
+func (l *Log) HighWatermark() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.nextOffset
+}
+
+// LogStartOffset returns the first offset still available in the log
+func (l *Log) LogStartOffset() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.logStartOffset
+}
+
+// LastStableOffset returns the offset up to which read_committed consumers may read,
+// which is the first offset of the oldest ongoing transaction
+func (l *Log) LastStableOffset() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.lastStableOffset()
+}
+
+func (l *Log) lastStableOffset() int64 {
+	lso := l.nextOffset
+	for _, producer := range l.producers {
+		if producer.txnFirstOffset >= 0 && producer.txnFirstOffset < lso {
+			lso = producer.txnFirstOffset
+		}
+	}
+	return lso
+}
+
+// OffsetForTimestamp returns the first record below limit whose timestamp is at least ts
+func (l *Log) OffsetForTimestamp(ts time.Time, limit int64) (*types.Record, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, entry := range l.entries[l.search(l.logStartOffset):] {
+		for _, r := range entry.Records(l.Topic, l.Partition) {
+			if r.Offset >= limit {
+				return nil, false
+			}
+			if r.Offset >= l.logStartOffset && !r.Timestamp.Before(ts) {
+				return r, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// MaxTimestamp returns the first record below limit with the largest timestamp
+func (l *Log) MaxTimestamp(limit int64) (*types.Record, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var max *types.Record
+	for _, entry := range l.entries[l.search(l.logStartOffset):] {
+		for _, r := range entry.Records(l.Topic, l.Partition) {
+			if r.Offset >= limit {
+				return max, max != nil
+			}
+			if r.Offset >= l.logStartOffset && (max == nil || r.Timestamp.After(max.Timestamp)) {
+				max = r
+			}
+		}
+	}
+	return max, max != nil
+}
+
+// Records returns every stored record with an offset of at least offset which is
+// not below the log start offset
+func (l *Log) Records(offset int64) []*types.Record {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if offset < l.logStartOffset {
+		offset = l.logStartOffset
+	}
+
+	var records []*types.Record
+	for _, entry := range l.entries[l.search(offset):] {
+		for _, r := range entry.Records(l.Topic, l.Partition) {
+			if r.Offset >= offset {
+				records = append(records, r)
+			}
+		}
+	}
+	return records
+}
+
+// Entries returns the stored batches and messages starting with the one containing offset
+func (l *Log) Entries(offset int64) []*Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]*Entry(nil), l.entries[l.search(offset):]...)
+}
+
+// search returns the index of the first entry containing offset or any later offset
+func (l *Log) search(offset int64) int {
+	return sort.Search(len(l.entries), func(i int) bool {
+		return l.entries[i].LastOffset >= offset
+	})
+}
+
+// Read returns the encoded entries starting with the one containing offset and ending
+// before maxOffset. Like a real broker the result is cut at maxBytes, which may leave a
+// partial trailing entry, unless minOneMessage is set and the first entry alone is
+// larger than maxBytes.
+func (l *Log) Read(offset, maxOffset int64, maxBytes int32, minOneMessage bool, magic int8) ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if offset < l.logStartOffset || offset > l.nextOffset {
+		return nil, protocol.ErrOffsetOutOfRange
+	}
+
+	limit := int(maxBytes)
+	buf := []byte{}
+	for i := l.search(offset); i < len(l.entries) && l.entries[i].BaseOffset < maxOffset; i++ {
+		b, err := l.entries[i].Encode(magic)
+		if err != nil {
+			return nil, err
+		}
+		if len(buf)+len(b) > limit {
+			if len(buf) == 0 && minOneMessage {
+				buf = append(buf, b...)
+			} else if len(buf) < limit {
+				buf = append(buf, b[:limit-len(buf)]...)
+			}
+			break
+		}
+		buf = append(buf, b...)
+	}
+	return buf, nil
+}
@@ -0,0 +1,266 @@
+package storage
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+)
+
+// Producer state constants of Kafka
+const (
+	noProducerEpoch = -1
+	noSequence      = -1
+	// numBatchesToRetain is how many batches per producer are kept to detect duplicates
+	numBatchesToRetain = 5
+)
+
+// batchMetadata identifies an appended batch of an idempotent producer
+type batchMetadata struct {
+	firstSeq    int32
+	lastSeq     int32
+	firstOffset int64
+	lastOffset  int64
+}
+
+// appendOrigin tells who appends to a log. Batches from the coordinators carry a
+// producer ID and epoch but no sequence numbers.
+type appendOrigin int
+
+const (
+	fromClient appendOrigin = iota
+	fromCoordinator
+)
+
+// checkProducer validates a batch of an idempotent producer. It returns the state of
+// the producer to update once the batch is appended, or the earlier append of the batch.
+func (l *Log) checkProducer(batch *protocol.RecordBatch, origin appendOrigin) (*producerState, *batchMetadata, error) {
+	if batch == nil || batch.ProducerID < 0 || batch.Control {
+		return nil, nil, nil
+	}
+	producer := l.producer(batch.ProducerID)
+	if origin == fromClient {
+		if duplicate, ok := producer.duplicate(batch); ok {
+			return nil, &duplicate, nil
+		}
+	}
+	if err := producer.check(batch, origin); err != nil {
+		return nil, nil, err
+	}
+	return producer, nil, nil
+}
+
+// producer returns the state of a producer, which is new if the producer never appended
+func (l *Log) producer(producerID int64) *producerState {
+	if producer, ok := l.producers[producerID]; ok {
+		return producer
+	}
+	return &producerState{epoch: noProducerEpoch, txnFirstOffset: -1}
+}
+
+// AppendMarker writes the COMMIT or ABORT control batch which ends the transaction
+// of a producer in the log
+func (l *Log) AppendMarker(producerID int64, producerEpoch int16, commit bool) (*AppendInfo, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	producer := l.producer(producerID)
+	if producerEpoch < producer.epoch {
+		return nil, protocol.ErrInvalidProducerEpoch
+	}
+
+	now := time.Now()
+	batch := &protocol.RecordBatch{
+		Version:          2,
+		CompressionLevel: protocol.CompressionLevelDefault,
+		Control:          true,
+		IsTransactional:  true,
+		FirstTimestamp:   now,
+		MaxTimestamp:     now,
+		ProducerID:       producerID,
+		ProducerEpoch:    producerEpoch,
+		FirstSequence:    noSequence,
+		Records:          []*protocol.Record{{Key: controlRecordKey(commit), Value: controlRecordValue()}},
+	}
+	entry, err := newBatchEntry(batch, l.nextOffset)
+	if err != nil {
+		return nil, err
+	}
+	entry.appended = now
+	l.entries = append(l.entries, entry)
+	l.nextOffset = entry.LastOffset + 1
+
+	if !commit && producer.txnFirstOffset >= 0 {
+		l.aborted = append(l.aborted, abortedTxn{
+			producerID:  producerID,
+			firstOffset: producer.txnFirstOffset,
+			lastOffset:  entry.BaseOffset,
+		})
+		sort.Slice(l.aborted, func(i, j int) bool { return l.aborted[i].firstOffset < l.aborted[j].firstOffset })
+	}
+	producer.endTxn(producerEpoch)
+	l.producers[producerID] = producer
+
+	return &AppendInfo{
+		FirstOffset:    entry.BaseOffset,
+		LastOffset:     entry.LastOffset,
+		LogStartOffset: l.logStartOffset,
+	}, nil
+}
+
+// abortedTxn is an aborted transaction of a producer, from its first offset up to
+// its ABORT marker
+type abortedTxn struct {
+	producerID  int64
+	firstOffset int64
+	lastOffset  int64
+}
+
+// AbortedTransactions returns the aborted transactions which overlap the offsets
+// from offset up to maxOffset, read_committed consumers drop their records
+func (l *Log) AbortedTransactions(offset, maxOffset int64) []*protocol.AbortedTransaction {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	aborted := []*protocol.AbortedTransaction{}
+	for _, txn := range l.aborted {
+		if txn.firstOffset >= maxOffset {
+			break
+		}
+		if txn.lastOffset >= offset {
+			aborted = append(aborted, &protocol.AbortedTransaction{ProducerID: txn.producerID, FirstOffset: txn.firstOffset})
+		}
+	}
+	return aborted
+}
+
+// isAborted tells whether a batch belongs to an aborted transaction
+func (l *Log) isAborted(batch *protocol.RecordBatch, offset int64) bool {
+	if !batch.IsTransactional || batch.Control {
+		return false
+	}
+	for _, txn := range l.aborted {
+		if txn.producerID == batch.ProducerID && txn.firstOffset <= offset && offset < txn.lastOffset {
+			return true
+		}
+	}
+	return false
+}
+
+// controlRecordKey encodes the key of a control record: its version and type
+func controlRecordKey(commit bool) []byte {
+	key := make([]byte, 4)
+	if commit {
+		key[3] = 1
+	}
+	return key
+}
+
+// controlRecordValue encodes the value of a transaction marker: its version and the
+// coordinator epoch, which is always 0 since the coordinator never moves
+func controlRecordValue() []byte {
+	return make([]byte, 6)
+}
+
+// producerState is what a partition remembers about an idempotent producer
+type producerState struct {
+	epoch   int16
+	batches []batchMetadata
+	// txnFirstOffset is the first offset of the ongoing transaction, or -1
+	txnFirstOffset int64
+}
+
+func (p *producerState) lastSeq() int32 {
+	if len(p.batches) == 0 {
+		return noSequence
+	}
+	return p.batches[len(p.batches)-1].lastSeq
+}
+
+// duplicate returns the metadata of an earlier append of the same batch
+func (p *producerState) duplicate(batch *protocol.RecordBatch) (batchMetadata, bool) {
+	if batch.ProducerEpoch != p.epoch {
+		return batchMetadata{}, false
+	}
+	lastSeq := lastSequence(batch)
+	for _, b := range p.batches {
+		if b.firstSeq == batch.FirstSequence && b.lastSeq == lastSeq {
+			return b, true
+		}
+	}
+	return batchMetadata{}, false
+}
+
+// check validates the epoch and sequence of a batch against the state of its producer
+func (p *producerState) check(batch *protocol.RecordBatch, origin appendOrigin) error {
+	if batch.ProducerEpoch < p.epoch {
+		return protocol.ErrInvalidProducerEpoch
+	}
+	if p.txnFirstOffset >= 0 && !batch.IsTransactional {
+		return protocol.ErrInvalidTxnState
+	}
+	if origin == fromCoordinator {
+		return nil
+	}
+	if batch.ProducerEpoch != p.epoch {
+		// A new epoch has to start its sequences over
+		if batch.FirstSequence != 0 {
+			if p.epoch != noProducerEpoch {
+				return protocol.ErrOutOfOrderSequenceNumber
+			}
+			return protocol.ErrUnknownProducerId
+		}
+		return nil
+	}
+	lastSeq := p.lastSeq()
+	if lastSeq == noSequence && batch.FirstSequence != 0 {
+		return protocol.ErrOutOfOrderSequenceNumber
+	}
+	if lastSeq != noSequence && !inSequence(lastSeq, batch.FirstSequence) {
+		return protocol.ErrOutOfOrderSequenceNumber
+	}
+	return nil
+}
+
+// update records an appended batch of the producer
+func (p *producerState) update(batch *protocol.RecordBatch, firstOffset, lastOffset int64) {
+	if batch.ProducerEpoch != p.epoch {
+		p.epoch = batch.ProducerEpoch
+		p.batches = nil
+	}
+	if batch.IsTransactional && p.txnFirstOffset < 0 {
+		p.txnFirstOffset = firstOffset
+	}
+	if batch.FirstSequence == noSequence {
+		return
+	}
+	p.batches = append(p.batches, batchMetadata{
+		firstSeq:    batch.FirstSequence,
+		lastSeq:     lastSequence(batch),
+		firstOffset: firstOffset,
+		lastOffset:  lastOffset,
+	})
+	if len(p.batches) > numBatchesToRetain {
+		p.batches = p.batches[1:]
+	}
+}
+
+// endTxn completes the ongoing transaction of the producer. Markers written with a
+// newer epoch fence the producer, whose sequences start over.
+func (p *producerState) endTxn(epoch int16) {
+	if epoch != p.epoch {
+		p.epoch = epoch
+		p.batches = nil
+	}
+	p.txnFirstOffset = -1
+}
+
+// lastSequence returns the sequence of the last record of a batch, sequences wrap around
+func lastSequence(batch *protocol.RecordBatch) int32 {
+	return int32((int64(batch.FirstSequence) + int64(batch.LastOffsetDelta)) % (math.MaxInt32 + 1))
+}
+
+func inSequence(lastSeq, nextSeq int32) bool {
+	return nextSeq == lastSeq+1 || lastSeq == math.MaxInt32 && nextSeq == 0
+}
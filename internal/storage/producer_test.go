@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+)
+
+func newTestLog() *Log {
+	return newLog("t", 0, DefaultConfig(), []int32{1})
+}
+
+// producerBatch returns a batch of n records of an idempotent producer
+func producerBatch(producerID int64, epoch int16, firstSeq int32, n int) *protocol.Records {
+	batch := &protocol.RecordBatch{
+		Version:         2,
+		FirstTimestamp:  time.Now(),
+		ProducerID:      producerID,
+		ProducerEpoch:   epoch,
+		FirstSequence:   firstSeq,
+		LastOffsetDelta: int32(n - 1),
+	}
+	for i := 0; i < n; i++ {
+		batch.Records = append(batch.Records, &protocol.Record{OffsetDelta: int64(i), Key: []byte("k"), Value: []byte("v")})
+	}
+	return &protocol.Records{RecordBatch: batch}
+}
+
+func appendBatch(t *testing.T, l *Log, records *protocol.Records) *AppendInfo {
+	t.Helper()
+	info, err := l.Append(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestSequences(t *testing.T) {
+	l := newTestLog()
+
+	if _, err := l.Append(producerBatch(1, 0, 3, 1)); err != protocol.ErrUnknownProducerId {
+		t.Fatalf("first batch of a producer not starting at 0: %v", err)
+	}
+	first := appendBatch(t, l, producerBatch(1, 0, 0, 2))
+	second := appendBatch(t, l, producerBatch(1, 0, 2, 3))
+	if first.FirstOffset != 0 || first.LastOffset != 1 || second.FirstOffset != 2 || second.LastOffset != 4 {
+		t.Fatalf("appended at %+v and %+v", first, second)
+	}
+
+	tests := []struct {
+		name    string
+		records *protocol.Records
+		want    error
+	}{
+		{"gap", producerBatch(1, 0, 6, 1), protocol.ErrOutOfOrderSequenceNumber},
+		{"overlap", producerBatch(1, 0, 1, 2), protocol.ErrOutOfOrderSequenceNumber},
+		{"older epoch", producerBatch(1, -1, 5, 1), protocol.ErrInvalidProducerEpoch},
+		{"new epoch not starting at 0", producerBatch(1, 1, 5, 1), protocol.ErrOutOfOrderSequenceNumber},
+		{"other producer not starting at 0", producerBatch(2, 0, 5, 1), protocol.ErrUnknownProducerId},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := l.Append(tt.records); err != tt.want {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if hw := l.HighWatermark(); hw != 5 {
+		t.Fatalf("rejected batches were appended, high watermark %d", hw)
+	}
+
+	// A new epoch starts its sequences over
+	appendBatch(t, l, producerBatch(1, 1, 0, 1))
+	if _, err := l.Append(producerBatch(1, 0, 5, 1)); err != protocol.ErrInvalidProducerEpoch {
+		t.Fatalf("batch of the fenced epoch: %v", err)
+	}
+}
+
+func TestDuplicateSequences(t *testing.T) {
+	l := newTestLog()
+	for seq := int32(0); seq < 6; seq++ {
+		appendBatch(t, l, producerBatch(1, 0, seq, 1))
+	}
+
+	// The batches still retained are answered with the offsets of their first append
+	info := appendBatch(t, l, producerBatch(1, 0, 2, 1))
+	if !info.Duplicate || info.FirstOffset != 2 || info.LastOffset != 2 {
+		t.Fatalf("appended duplicate %+v", info)
+	}
+	if hw := l.HighWatermark(); hw != 6 {
+		t.Fatalf("duplicate was appended, high watermark %d", hw)
+	}
+	// Only the last numBatchesToRetain batches are remembered
+	if _, err := l.Append(producerBatch(1, 0, 0, 1)); err != protocol.ErrOutOfOrderSequenceNumber {
+		t.Fatalf("duplicate of a forgotten batch: %v", err)
+	}
+	// The same sequences with another epoch are not duplicates
+	if info := appendBatch(t, l, producerBatch(1, 1, 0, 1)); info.Duplicate || info.FirstOffset != 6 {
+		t.Fatalf("appended %+v", info)
+	}
+}
+
+func TestSequenceWrapsAround(t *testing.T) {
+	l := newTestLog()
+	appendBatch(t, l, producerBatch(1, 0, 0, 1))
+	l.producers[1].batches[0].lastSeq = math.MaxInt32 - 1
+
+	info := appendBatch(t, l, producerBatch(1, 0, math.MaxInt32, 2))
+	if info.FirstOffset != 1 || info.LastOffset != 2 {
+		t.Fatalf("appended %+v", info)
+	}
+	if seq := l.producers[1].lastSeq(); seq != 0 {
+		t.Fatalf("last sequence %d after wrapping around", seq)
+	}
+	appendBatch(t, l, producerBatch(1, 0, 1, 1))
+}
+
+func TestCoordinatorBatchesWithoutSequences(t *testing.T) {
+	l := newTestLog()
+	records := producerBatch(1, 0, noSequence, 1)
+	for i := 0; i < 2; i++ {
+		if _, err := l.append(records, fromCoordinator); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if hw := l.HighWatermark(); hw != 2 {
+		t.Fatalf("high watermark %d", hw)
+	}
+	// The batches of the coordinator start no sequence the producer has to follow
+	appendBatch(t, l, producerBatch(1, 0, 0, 1))
+}
+
+// txnBatch returns a transactional batch of n records
+func txnBatch(producerID int64, epoch int16, firstSeq int32, n int) *protocol.Records {
+	records := producerBatch(producerID, epoch, firstSeq, n)
+	records.RecordBatch.IsTransactional = true
+	return records
+}
+
+func appendMarker(t *testing.T, l *Log, producerID int64, epoch int16, commit bool) *AppendInfo {
+	t.Helper()
+	info, err := l.AppendMarker(producerID, epoch, commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func checkOffsets(t *testing.T, l *Log, lso, hw int64) {
+	t.Helper()
+	if got := l.LastStableOffset(); got != lso {
+		t.Fatalf("last stable offset %d, want %d", got, lso)
+	}
+	if got := l.HighWatermark(); got != hw {
+		t.Fatalf("high watermark %d, want %d", got, hw)
+	}
+}
+
+func TestLastStableOffset(t *testing.T) {
+	l := newTestLog()
+	appendBatch(t, l, producerBatch(1, 0, 0, 1))
+	checkOffsets(t, l, 1, 1)
+
+	// The oldest ongoing transaction holds back the last stable offset
+	appendBatch(t, l, txnBatch(2, 0, 0, 2))
+	appendBatch(t, l, txnBatch(3, 0, 0, 1))
+	appendBatch(t, l, producerBatch(1, 0, 1, 1))
+	checkOffsets(t, l, 1, 5)
+
+	if _, err := l.Append(producerBatch(2, 0, 2, 1)); err != protocol.ErrInvalidTxnState {
+		t.Fatalf("non transactional batch within a transaction: %v", err)
+	}
+	if _, err := l.AppendMarker(2, -1, true); err != protocol.ErrInvalidProducerEpoch {
+		t.Fatalf("marker of an older epoch: %v", err)
+	}
+
+	commit := appendMarker(t, l, 2, 0, true)
+	if commit.FirstOffset != 5 {
+		t.Fatalf("marker appended at %d", commit.FirstOffset)
+	}
+	checkOffsets(t, l, 3, 6)
+	appendMarker(t, l, 3, 0, false)
+	checkOffsets(t, l, 7, 7)
+
+	// The next transaction of a producer starts at its first batch
+	appendBatch(t, l, txnBatch(2, 0, 2, 1))
+	checkOffsets(t, l, 7, 8)
+}
+
+func TestAbortedTransactions(t *testing.T) {
+	l := newTestLog()
+	// Producer 1 aborts offsets 0 to 3, producer 2 commits 1 to 4 and producer 3
+	// aborts 5 to 7 in an epoch which fences its transaction
+	appendBatch(t, l, txnBatch(1, 0, 0, 1))
+	appendBatch(t, l, txnBatch(2, 0, 0, 1))
+	appendBatch(t, l, txnBatch(1, 0, 1, 1))
+	appendMarker(t, l, 1, 0, false)
+	appendMarker(t, l, 2, 0, true)
+	appendBatch(t, l, txnBatch(3, 0, 0, 2))
+	appendMarker(t, l, 3, 1, false)
+	checkOffsets(t, l, 8, 8)
+
+	tests := []struct {
+		offset, maxOffset int64
+		// want holds the producer ID and first offset of each aborted transaction
+		want [][2]int64
+	}{
+		{0, 8, [][2]int64{{1, 0}, {3, 5}}},
+		{0, 1, [][2]int64{{1, 0}}},
+		{3, 5, [][2]int64{{1, 0}}},
+		{4, 5, nil},
+		{4, 6, [][2]int64{{3, 5}}},
+		{7, 8, [][2]int64{{3, 5}}},
+		{8, 8, nil},
+	}
+	for _, tt := range tests {
+		aborted := l.AbortedTransactions(tt.offset, tt.maxOffset)
+		if len(aborted) != len(tt.want) {
+			t.Fatalf("aborted transactions from %d to %d: %+v", tt.offset, tt.maxOffset, aborted)
+		}
+		for i, txn := range aborted {
+			if txn.ProducerID != tt.want[i][0] || txn.FirstOffset != tt.want[i][1] {
+				t.Fatalf("aborted transactions from %d to %d: %+v", tt.offset, tt.maxOffset, aborted)
+			}
+		}
+	}
+
+	for _, entry := range l.Entries(0) {
+		want := entry.Batch.ProducerID != 2 && !entry.Batch.Control
+		if got := l.isAborted(entry.Batch, entry.BaseOffset); got != want {
+			t.Fatalf("batch of producer %d at %d aborted: %t", entry.Batch.ProducerID, entry.BaseOffset, got)
+		}
+	}
+
+	// The fenced producer starts over with the epoch of the marker
+	appendBatch(t, l, txnBatch(3, 1, 0, 1))
+	if _, err := l.Append(txnBatch(3, 0, 2, 1)); err != protocol.ErrInvalidProducerEpoch {
+		t.Fatalf("batch of the fenced epoch: %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+// In-memory storage for the produced records
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+	"github.com/ninepub/kafka-mock/pkg/types"
+)
+
+const maxTopicNameLength = 249
+
+// Store holds the partition logs of every topic known to the mock
+type Store struct {
+	mu      sync.RWMutex
+	topics  map[string]map[int32]*Log
+	configs map[string]*Config
+	changed chan struct{}
+}
+
+func NewStore() *Store {
+	return &Store{
+		topics:  make(map[string]map[int32]*Log),
+		configs: make(map[string]*Config),
+		changed: make(chan struct{}),
+	}
+}
+
+// CreateTopic creates empty logs for the partitions missing from a topic, replicas
+// lists the replicas of every partition. A nil config uses the default configs, the
+// config of an existing topic is kept. It reports whether the topic did not exist yet.
+func (s *Store) CreateTopic(topic string, replicas [][]int32, config *Config) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	created := s.topics[topic] == nil
+	if created {
+		if config == nil {
+			config = DefaultConfig()
+		}
+		s.topics[topic] = make(map[int32]*Log)
+		s.configs[topic] = config
+	}
+	for p := range replicas {
+		if s.topics[topic][int32(p)] == nil {
+			s.topics[topic][int32(p)] = newLog(topic, int32(p), s.configs[topic], replicas[p])
+		}
+	}
+	return created
+}
+
+// DeleteTopic removes a topic and all of its records, it reports whether the topic existed
+func (s *Store) DeleteTopic(topic string) bool {
+	s.mu.Lock()
+	if s.topics[topic] == nil {
+		s.mu.Unlock()
+		return false
+	}
+	delete(s.topics, topic)
+	delete(s.configs, topic)
+	s.mu.Unlock()
+
+	// Wake up fetches waiting on the deleted partitions
+	s.notify()
+	return true
+}
+
+// Config returns the configs of a topic, or nil if the topic does not exist
+func (s *Store) Config(topic string) *Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.configs[topic]
+}
+
+// Clean deletes the records which exceed the retention of their topic and compacts
+// the compacted topics, like the log cleaner of Kafka does in the background. A log
+// which fails to be cleaned does not stop the others, the errors of all of them are
+// returned together as a CleanErrors.
+func (s *Store) Clean() error {
+	s.mu.RLock()
+	var logs []*Log
+	for _, partitions := range s.topics {
+		for _, l := range partitions {
+			logs = append(logs, l)
+		}
+	}
+	s.mu.RUnlock()
+
+	now := time.Now()
+	var errs CleanErrors
+	for _, l := range logs {
+		if err := l.clean(now); err != nil {
+			errs = append(errs, fmt.Errorf("failed to clean %s-%d: %w", l.Topic, l.Partition, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// CleanErrors holds the error of each log which failed to be cleaned
+type CleanErrors []error
+
+func (e CleanErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the errors, errors.Is and errors.As look into each of them since Go 1.20
+func (e CleanErrors) Unwrap() []error {
+	return e
+}
+
+// ValidTopicName reports whether Kafka accepts name as a topic name
+func ValidTopicName(name string) bool {
+	if name == "" || name == "." || name == ".." || len(name) > maxTopicNameLength {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// Log returns the log of a topic partition, if it exists
+func (s *Store) Log(topic string, partition int32) (*Log, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	l, ok := s.topics[topic][partition]
+	return l, ok
+}
+
+// Topics returns the names of all topics in the store
+func (s *Store) Topics() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	topics := make([]string, 0, len(s.topics))
+	for topic := range s.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Partitions returns the partition IDs of a topic, or nil if the topic does not exist
+func (s *Store) Partitions(topic string) []int32 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.topics[topic] == nil {
+		return nil
+	}
+	partitions := make([]int32, 0, len(s.topics[topic]))
+	for p := range s.topics[topic] {
+		partitions = append(partitions, p)
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+	return partitions
+}
+
+// Records returns every record stored in a topic partition
+func (s *Store) Records(topic string, partition int32) []*types.Record {
+	l, ok := s.Log(topic, partition)
+	if !ok {
+		return nil
+	}
+	return l.Records(0)
+}
+
+// Append adds the records to the log of an existing topic partition
+func (s *Store) Append(topic string, partition int32, records *protocol.Records) (*AppendInfo, error) {
+	l, ok := s.Log(topic, partition)
+	if !ok {
+		return nil, protocol.ErrUnknownTopicOrPartition
+	}
+
+	info, err := l.Append(records)
+	if err != nil {
+		return nil, err
+	}
+	s.notify()
+	return info, nil
+}
+
+// AppendFromCoordinator adds records written by a coordinator, like the transactional
+// offset commits of a group, whose batches have no sequence numbers
+func (s *Store) AppendFromCoordinator(topic string, partition int32, records *protocol.Records) (*AppendInfo, error) {
+	l, ok := s.Log(topic, partition)
+	if !ok {
+		return nil, protocol.ErrUnknownTopicOrPartition
+	}
+
+	info, err := l.append(records, fromCoordinator)
+	if err != nil {
+		return nil, err
+	}
+	s.notify()
+	return info, nil
+}
+
+// AppendMarker ends the transaction of a producer in a topic partition
+func (s *Store) AppendMarker(topic string, partition int32, producerID int64, producerEpoch int16, commit bool) (*AppendInfo, error) {
+	l, ok := s.Log(topic, partition)
+	if !ok {
+		return nil, protocol.ErrUnknownTopicOrPartition
+	}
+
+	info, err := l.AppendMarker(producerID, producerEpoch, commit)
+	if err != nil {
+		return nil, err
+	}
+	s.notify()
+	return info, nil
+}
+
+// Changed returns a channel which is closed on the next append to any log
+func (s *Store) Changed() <-chan struct{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.changed
+}
+
+func (s *Store) notify() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+)
+
+func TestCleanContinuesAfterFailure(t *testing.T) {
+	s := NewStore()
+	for _, topic := range []string{"broken", "ok"} {
+		config, err := NewConfig(map[string]string{"cleanup.policy": "compact"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.CreateTopic(topic, [][]int32{{1}}, config)
+		// The first batch has to be encoded again once the record replaced by the
+		// active entry is removed from it
+		first := producerBatch(-1, -1, noSequence, 2)
+		first.RecordBatch.Records[1].Key = []byte("kept")
+		for _, records := range []*protocol.Records{first, producerBatch(-1, -1, noSequence, 1)} {
+			if _, err := s.Append(topic, 0, records); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// A batch the cleaner fails to encode again
+	broken, _ := s.Log("broken", 0)
+	broken.entries[0].Batch.Version = 1
+
+	err := s.Clean()
+	errs, ok := err.(CleanErrors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("cleaned with %v", err)
+	}
+	if records := s.Records("ok", 0); len(records) != 2 || records[0].Offset != 1 || records[1].Offset != 2 {
+		t.Fatalf("log after the failed one was not compacted: %+v", records)
+	}
+}
@@ -0,0 +1,208 @@
+// Transaction coordination
+package txn
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ninepub/kafka-mock/internal/protocol"
+)
+
+// DefaultMaxTimeout is the default of transaction.max.timeout.ms
+const DefaultMaxTimeout = 15 * time.Minute
+
+// Markers asks for the COMMIT or ABORT markers of a transaction to be written to its partitions
+type Markers struct {
+	ProducerID    int64
+	ProducerEpoch int16
+	Commit        bool
+	Partitions    map[string][]int32
+}
+
+// MarkerWriter writes the markers of a transaction, it is called with the state of the
+// transaction locked so that the transaction is complete once the call returns
+type MarkerWriter func(markers *Markers)
+
+// Coordinator hands out producer IDs and epochs and runs the transactions of the
+// transactional producers
+type Coordinator struct {
+	writeMarkers MarkerWriter
+
+	mu             sync.Mutex
+	nextProducerID int64
+	transactions   map[string]*transaction
+	closed         bool
+}
+
+func NewCoordinator(writeMarkers MarkerWriter) *Coordinator {
+	return &Coordinator{
+		writeMarkers: writeMarkers,
+		transactions: make(map[string]*transaction),
+	}
+}
+
+// InitProducerID returns a new producer ID with epoch 0 for a producer without a
+// transactional ID. A transactional producer keeps its producer ID with a new epoch,
+// which fences its previous instances, and its ongoing transaction is aborted.
+func (c *Coordinator) InitProducerID(transactionalID *string, timeout time.Duration) (int64, int16, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if transactionalID == nil {
+		return c.newProducerID(), 0, nil
+	}
+	if *transactionalID == "" {
+		return -1, -1, protocol.ErrInvalidRequest
+	}
+	if timeout <= 0 || timeout > DefaultMaxTimeout {
+		return -1, -1, protocol.ErrInvalidTransactionTimeout
+	}
+
+	t, ok := c.transactions[*transactionalID]
+	if !ok {
+		t = &transaction{id: *transactionalID, producerID: c.newProducerID(), timeout: timeout, state: Empty}
+		c.transactions[t.id] = t
+		return t.producerID, t.producerEpoch, nil
+	}
+
+	if t.state == Ongoing {
+		// The markers are written with the new epoch, which fences the old producer
+		c.complete(t, t.bumpEpoch(), false)
+	} else {
+		t.bumpEpoch()
+	}
+	if t.producerEpoch == math.MaxInt16 {
+		t.producerID = c.newProducerID()
+		t.producerEpoch = 0
+	}
+	t.timeout = timeout
+	t.state = Empty
+	t.partitions = nil
+	return t.producerID, t.producerEpoch, nil
+}
+
+func (c *Coordinator) newProducerID() int64 {
+	id := c.nextProducerID
+	c.nextProducerID++
+	return id
+}
+
+// AddPartitions adds partitions to the transaction of a producer, starting the
+// transaction if none is ongoing
+func (c *Coordinator) AddPartitions(transactionalID string, producerID int64, producerEpoch int16, partitions map[string][]int32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.transaction(transactionalID, producerID, producerEpoch)
+	if err != nil {
+		return err
+	}
+	if t.state != Ongoing {
+		t.state = Ongoing
+		t.partitions = make(map[string]map[int32]bool)
+		t.startTimer(c)
+	}
+	for topic, ps := range partitions {
+		if t.partitions[topic] == nil {
+			t.partitions[topic] = make(map[int32]bool)
+		}
+		for _, p := range ps {
+			t.partitions[topic][p] = true
+		}
+	}
+	return nil
+}
+
+// Check verifies that a producer is the current one of a transaction and that the
+// transaction includes the given partition, as offsets committed within it require
+func (c *Coordinator) Check(transactionalID string, producerID int64, producerEpoch int16, topic string, partition int32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.transaction(transactionalID, producerID, producerEpoch)
+	if err != nil {
+		return err
+	}
+	if t.state != Ongoing || !t.partitions[topic][partition] {
+		return protocol.ErrInvalidTxnState
+	}
+	return nil
+}
+
+// EndTxn commits or aborts the ongoing transaction of a producer. Retries of a
+// completed EndTxn succeed again.
+func (c *Coordinator) EndTxn(transactionalID string, producerID int64, producerEpoch int16, commit bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.transaction(transactionalID, producerID, producerEpoch)
+	if err != nil {
+		return err
+	}
+	switch {
+	case t.state == Ongoing:
+		c.complete(t, t.producerEpoch, commit)
+		return nil
+	case t.state == CompleteCommit && commit, t.state == CompleteAbort && !commit:
+		return nil
+	default:
+		return protocol.ErrInvalidTxnState
+	}
+}
+
+// Close stops the transaction timers
+func (c *Coordinator) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for _, t := range c.transactions {
+		t.stopTimer()
+	}
+}
+
+// transaction returns the transaction of a transactional ID after checking that the
+// producer is its current one
+func (c *Coordinator) transaction(transactionalID string, producerID int64, producerEpoch int16) (*transaction, error) {
+	t, ok := c.transactions[transactionalID]
+	if !ok || t.producerID != producerID {
+		return nil, protocol.ErrInvalidProducerIdMapping
+	}
+	if t.producerEpoch != producerEpoch {
+		return nil, protocol.ErrInvalidProducerEpoch
+	}
+	return t, nil
+}
+
+// complete writes the markers of an ongoing transaction with the given epoch
+func (c *Coordinator) complete(t *transaction, epoch int16, commit bool) {
+	t.stopTimer()
+	markers := &Markers{
+		ProducerID:    t.producerID,
+		ProducerEpoch: epoch,
+		Commit:        commit,
+		Partitions:    make(map[string][]int32),
+	}
+	for topic, ps := range t.partitions {
+		for p := range ps {
+			markers.Partitions[topic] = append(markers.Partitions[topic], p)
+		}
+	}
+	c.writeMarkers(markers)
+
+	t.state = CompleteAbort
+	if commit {
+		t.state = CompleteCommit
+	}
+}
+
+// expire aborts a transaction which did not complete within its timeout. Like Kafka
+// the epoch is bumped, so the producer is fenced.
+func (c *Coordinator) expire(t *transaction, timerID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || t.timerID != timerID || t.state != Ongoing {
+		return
+	}
+	c.complete(t, t.bumpEpoch(), false)
+}
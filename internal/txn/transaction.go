@@ -0,0 +1,62 @@
+package txn
+
+import "time"
+
+// State is the state of a transaction
+type State int
+
+const (
+	Empty State = iota
+	Ongoing
+	CompleteCommit
+	CompleteAbort
+)
+
+func (s State) String() string {
+	switch s {
+	case Empty:
+		return "Empty"
+	case Ongoing:
+		return "Ongoing"
+	case CompleteCommit:
+		return "CompleteCommit"
+	case CompleteAbort:
+		return "CompleteAbort"
+	default:
+		return "Unknown"
+	}
+}
+
+// transaction is the state of a transactional ID. The mock writes the markers right
+// away, so it never stays in the PrepareCommit and PrepareAbort states of Kafka.
+type transaction struct {
+	id            string
+	producerID    int64
+	producerEpoch int16
+	timeout       time.Duration
+	state         State
+	partitions    map[string]map[int32]bool
+	timer         *time.Timer
+	// timerID tells the expiry of the current timer apart from those of stopped ones
+	timerID int
+}
+
+// bumpEpoch moves the transaction to the next producer epoch and returns it
+func (t *transaction) bumpEpoch() int16 {
+	t.producerEpoch++
+	return t.producerEpoch
+}
+
+func (t *transaction) startTimer(c *Coordinator) {
+	t.stopTimer()
+	t.timerID++
+	id := t.timerID
+	t.timer = time.AfterFunc(t.timeout, func() { c.expire(t, id) })
+}
+
+func (t *transaction) stopTimer() {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"strconv"
+
+	"github.com/ninepub/kafka-mock/internal/server"
+	"github.com/ninepub/kafka-mock/pkg/types"
+)
+
+// listenerConfigs returns the listeners of the params, or else the single listener
+// of Addr, Port, TLS and SASL named after its security protocol like in Kafka
+func (s *Server) listenerConfigs() []types.Listener {
+	if len(s.params.Listeners) > 0 {
+		return s.params.Listeners
+	}
+	name := "PLAINTEXT"
+	switch {
+	case s.params.TLS != nil && s.params.SASL != nil:
+		name = "SASL_SSL"
+	case s.params.TLS != nil:
+		name = "SSL"
+	case s.params.SASL != nil:
+		name = "SASL_PLAINTEXT"
+	}
+	return []types.Listener{{
+		Name: name,
+		Addr: s.params.Addr,
+		Port: s.params.Port,
+		TLS:  s.params.TLS,
+		SASL: s.params.SASL,
+	}}
+}
+
+// brokerListener returns the listener config of the broker with the given index out
+// of count listeners. The ports of the brokers after the first are shifted by their
+// index times count, so that listeners on adjacent ports never collide. A port of 0
+// still picks a free one.
+func brokerListener(config types.Listener, index, count int) types.Listener {
+	if config.Port != 0 {
+		config.Port += index * count
+	}
+	if config.AdvertisedPort != 0 {
+		config.AdvertisedPort += index * count
+	}
+	return config
+}
+
+// listen binds a listener and returns it with the endpoint it advertises
+func (s *Server) listen(config types.Listener) (net.Listener, *server.Listener, error) {
+	if config.Name == "" {
+		return nil, nil, errors.New("kafka-mock: listener without a name")
+	}
+	host := "127.0.0.1"
+	if config.Addr != "" {
+		host = config.Addr
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(config.Addr, strconv.Itoa(config.Port)))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	l := &server.Listener{
+		Name: config.Name,
+		Host: config.AdvertisedHost,
+		Port: int32(config.AdvertisedPort),
+		SASL: config.SASL,
+	}
+	if l.Host == "" {
+		l.Host = host
+	}
+	if l.Port == 0 {
+		l.Port = int32(listener.Addr().(*net.TCPAddr).Port)
+	}
+
+	if config.TLS != nil {
+		tlsConfig, err := s.tlsConfig(config.TLS, host, l.Host)
+		if err != nil {
+			listener.Close()
+			return nil, nil, err
+		}
+		l.TLS = tlsConfig
+	}
+	return listener, l, nil
+}
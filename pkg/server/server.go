@@ -2,33 +2,315 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"github.com/ninepub/kafka-mock/internal/message"
-	"github.com/ninepub/kafka-mock/internal/server"
-	"github.com/ninepub/kafka-mock/pkg/types"
 	"net"
 	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ninepub/kafka-mock/internal/certs"
+	"github.com/ninepub/kafka-mock/internal/server"
+	"github.com/ninepub/kafka-mock/pkg/types"
 )
 
-func StartKafka(params *types.Params) {
-	// Initialize the response messages
-	message.UpdateResponses(params)
+// ErrServerClosed is returned by Start once the server has been closed
+var ErrServerClosed = errors.New("kafka-mock: server closed")
 
-	fmt.Println("Starting server...")
+// Server is a mock Kafka cluster of one or more brokers. Every server has its own
+// state, so many of them can run side by side in the same process.
+type Server struct {
+	params  *types.Params
+	cluster *server.Cluster
+	// ca signs the certificates of TLS servers
+	ca *certs.CA
 
-	src := params.Addr + ":" + strconv.Itoa(params.Port)
-	listener, err := net.Listen("tcp", src)
-	if err != nil {
-		fmt.Printf("Failed to start the server: %s\n", err)
+	mu        sync.Mutex
+	listeners []net.Listener
+	// addrs are the advertised host:port of the listeners of the first broker by name
+	addrs map[string]string
+	addr  string
+	// brokerAddrs are the advertised host:port of the first listener of every broker
+	brokerAddrs []string
+	conns       map[net.Conn]struct{}
+	closed      bool
+	wg          sync.WaitGroup
+	done        chan struct{}
+}
+
+// New creates a server for the given params. It does not listen until Start is called.
+func New(params *types.Params) *Server {
+	return &Server{
+		params:  params,
+		cluster: server.NewCluster(params),
+		addrs:   make(map[string]string),
+		conns:   make(map[net.Conn]struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start listens on the configured address and serves connections in the background.
+// A port of 0 picks a free ephemeral port, use Addr to find out which one. With many
+// brokers each one listens on the configured ports plus its index times the number
+// of listeners.
+// The server is closed when ctx is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrServerClosed
+	}
+	if s.listeners != nil {
+		return errors.New("kafka-mock: server already started")
+	}
+
+	var listeners []net.Listener
+	var advertised []*server.Listener
+	var brokers []*server.Broker
+	// closeListeners undoes a failed start, so that it can be tried again
+	closeListeners := func() {
+		for i, listener := range listeners {
+			listener.Close()
+			brokers[i].RemoveListener(advertised[i].Name)
+		}
+	}
+	configs := s.listenerConfigs()
+	for i, broker := range s.cluster.Brokers() {
+		for _, config := range configs {
+			listener, l, err := s.listen(brokerListener(config, i, len(configs)))
+			if err != nil {
+				closeListeners()
+				return err
+			}
+			if err := broker.AddListener(l); err != nil {
+				listener.Close()
+				closeListeners()
+				return err
+			}
+			listeners = append(listeners, listener)
+			advertised = append(advertised, l)
+			brokers = append(brokers, broker)
+		}
+	}
+	if err := s.cluster.Start(); err != nil {
+		closeListeners()
+		return err
 	}
-	fmt.Printf("Listening on %s.\n", src)
-	defer listener.Close()
 
+	s.listeners = listeners
+	first := brokers[0]
+	for i, listener := range listeners {
+		l, broker := advertised[i], brokers[i]
+		fmt.Printf("Broker %d listening on %s.\n", broker.ID(), listener.Addr())
+		addr := net.JoinHostPort(l.Host, strconv.Itoa(int(l.Port)))
+		if i == 0 || broker != brokers[i-1] {
+			s.brokerAddrs = append(s.brokerAddrs, addr)
+		}
+		if broker == first {
+			s.addrs[l.Name] = addr
+		}
+		s.wg.Add(1)
+		go s.serve(listener, broker, l)
+	}
+	s.addr = s.brokerAddrs[0]
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Close()
+		case <-s.done:
+		}
+	}()
+	return nil
+}
+
+func (s *Server) serve(listener net.Listener, broker *server.Broker, l *server.Listener) {
+	defer s.wg.Done()
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
+			if s.isClosed() {
+				return
+			}
 			fmt.Printf("Some connection error: %s\n", err)
+			continue
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			broker.HandleConnection(conn, l)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Addr returns the host:port clients can connect to, or "" if the server is not started.
+// It reports the actual port when the server was configured with port 0. With many
+// listeners it is the address of the first one, with many brokers that of the first broker.
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addr
+}
+
+// ListenerAddr returns the advertised host:port of the listener with the given name
+// of the first broker
+func (s *Server) ListenerAddr(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addrs[name]
+}
+
+// Addrs returns the host:port of every broker, ordered by node ID, to use as the
+// bootstrap servers of clients. With many listeners they are those of the first one.
+func (s *Server) Addrs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.brokerAddrs...)
+}
+
+// Close stops the server and waits for the open connections to finish their current request
+func (s *Server) Close() error {
+	return s.Shutdown(context.Background())
+}
+
+// Shutdown stops accepting connections and lets every open connection finish its
+// current request. Connections still busy when ctx is done are closed forcefully.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		s.wg.Wait()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+
+	var err error
+	for _, listener := range s.listeners {
+		if cerr := listener.Close(); err == nil {
+			err = cerr
+		}
+	}
+	s.cluster.Close()
+	// Wake up connections blocked reading the next request, busy ones stop after responding
+	for conn := range s.conns {
+		conn.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		s.mu.Lock()
+		for conn := range s.conns {
+			conn.Close()
 		}
-		go server.HandleConnection(conn, params)
+		s.mu.Unlock()
+		<-drained
+	}
+	return err
+}
+
+// Wait blocks until the server is closed and all connections are drained
+func (s *Server) Wait() {
+	<-s.done
+	s.wg.Wait()
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// Records returns every record produced to a topic partition, in offset order
+func (s *Server) Records(topic string, partition int32) []*types.Record {
+	return s.cluster.Store().Records(topic, partition)
+}
+
+// Topics returns the names of all topics holding partition logs
+func (s *Server) Topics() []string {
+	return s.cluster.Store().Topics()
+}
+
+// CommittedOffset returns the offset a consumer group committed for a topic partition
+func (s *Server) CommittedOffset(group, topic string, partition int32) (int64, bool) {
+	offset, ok := s.cluster.Groups().Offsets().Fetch(group, topic, partition)
+	if !ok {
+		return -1, false
+	}
+	return offset.Offset, true
+}
+
+// Handle registers a handler for the versions from minVersion to maxVersion of an
+// API, on every broker. It overrides the built-in handler and earlier registrations
+// for these versions and can be called while the server is running.
+func (s *Server) Handle(key, minVersion, maxVersion int16, h types.Handler) error {
+	return s.cluster.Handle(key, minVersion, maxVersion, h)
+}
+
+// HandleFunc registers a handler function like Handle
+func (s *Server) HandleFunc(key, minVersion, maxVersion int16, f func(w types.ResponseWriter, r *types.Request) error) error {
+	return s.cluster.Handle(key, minVersion, maxVersion, types.HandlerFunc(f))
+}
+
+// Use adds middleware wrapping every handler, built-in or registered. The first
+// middleware added sees the requests first.
+func (s *Server) Use(middleware ...types.Middleware) {
+	s.cluster.Use(middleware...)
+}
+
+// AddFault starts injecting a fault into the matching requests. It can be called
+// while the server is running.
+func (s *Server) AddFault(f types.Fault) error {
+	return s.cluster.AddFault(f)
+}
+
+// ClearFaults stops injecting the faults added so far, including those of the params
+func (s *Server) ClearFaults() {
+	s.cluster.ClearFaults()
+}
+
+// SetConnectionFaults replaces the faults disturbing the connections of every
+// broker, like latency or connections closed mid-response. The zero value clears
+// them. It can be called while the server is running.
+func (s *Server) SetConnectionFaults(f types.ConnectionFaults) {
+	s.cluster.SetConnectionFaults(f)
+}
+
+// RefuseConnections resets the connections accepted during the given period, as if
+// the brokers were down
+func (s *Server) RefuseConnections(d time.Duration) {
+	s.cluster.RefuseConnections(d)
+}
+
+// StartKafka starts a server and blocks until it is closed
+func StartKafka(params *types.Params) {
+	fmt.Println("Starting server...")
+
+	s := New(params)
+	if err := s.Start(context.Background()); err != nil {
+		fmt.Printf("Failed to start the server: %s\n", err)
+		return
 	}
+	s.Wait()
 }
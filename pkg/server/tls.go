@@ -0,0 +1,82 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ninepub/kafka-mock/internal/certs"
+	"github.com/ninepub/kafka-mock/pkg/types"
+)
+
+// tlsConfig builds the TLS config of a listener. The broker certificate is issued
+// for the bind and advertised hosts by the generated CA unless the params name
+// certificate files. All listeners share the same CA.
+func (s *Server) tlsConfig(params *types.TLS, host, advertisedHost string) (*tls.Config, error) {
+	var err error
+	if s.ca == nil {
+		if s.ca, err = certs.NewCA(); err != nil {
+			return nil, err
+		}
+	}
+
+	config := &tls.Config{ClientCAs: s.ca.Pool()}
+	var cert tls.Certificate
+	if params.CertFile != "" || params.KeyFile != "" {
+		cert, err = tls.LoadX509KeyPair(params.CertFile, params.KeyFile)
+	} else {
+		cert, err = s.ca.Server(host, advertisedHost, "localhost", "127.0.0.1", "::1")
+	}
+	if err != nil {
+		return nil, err
+	}
+	config.Certificates = []tls.Certificate{cert}
+
+	if params.CAFile != "" {
+		data, err := ioutil.ReadFile(params.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificate found in %s", params.CAFile)
+		}
+	}
+
+	switch params.ClientAuth {
+	case "", types.ClientAuthNone:
+		config.ClientAuth = tls.NoClientCert
+	case types.ClientAuthRequested:
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	case types.ClientAuthRequired:
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("invalid TLS client auth %q", params.ClientAuth)
+	}
+	return config, nil
+}
+
+// CACertificate returns the PEM encoded certificate of the CA generated for TLS,
+// which clients have to trust unless the broker certificate comes from TLS.CertFile.
+// It is nil until a TLS server is started.
+func (s *Server) CACertificate() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ca == nil {
+		return nil
+	}
+	return s.ca.PEM()
+}
+
+// ClientCertificate issues a client certificate signed by the generated CA. The
+// principal of the clients using it is User:CN=commonName.
+func (s *Server) ClientCertificate(commonName string) (tls.Certificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ca == nil {
+		return tls.Certificate{}, errors.New("kafka-mock: TLS is not enabled")
+	}
+	return s.ca.Client(commonName)
+}
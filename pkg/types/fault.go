@@ -0,0 +1,63 @@
+package types
+
+import "time"
+
+// Fault makes the broker answer the requests matching all of its conditions with an
+// error instead of serving them. Faults apply to every built-in API but the SASL ones.
+type Fault struct {
+	// API matches the requests of an API by name, like Produce or ListOffsets, or by
+	// key. Empty matches every API.
+	API string `json:"api" yaml:"api"`
+	// Topic matches the requests for a topic and Partitions those for some of its
+	// partitions. Only the matching topics and partitions of a request fail, APIs
+	// which do not address topics never match.
+	Topic      string  `json:"topic" yaml:"topic"`
+	Partitions []int32 `json:"partitions" yaml:"partitions"`
+	// ClientID matches the requests of a client
+	ClientID string `json:"clientId" yaml:"clientId"`
+	// Request starts injecting the fault at the Nth request matching the other
+	// conditions, counting from 1
+	Request int `json:"request" yaml:"request"`
+
+	// Error is the error returned, by name like NOT_LEADER_FOR_PARTITION or
+	// REQUEST_TIMED_OUT, or by code
+	Error string `json:"error" yaml:"error"`
+	// Mode selects how many matching requests fail, it defaults to FaultOnce
+	Mode FaultMode `json:"mode" yaml:"mode"`
+	// Times is the number of requests failing with FaultTimes
+	Times int `json:"times" yaml:"times"`
+	// Probability is the chance of each request to fail with FaultProbability,
+	// between 0 and 1
+	Probability float64 `json:"probability" yaml:"probability"`
+}
+
+// FaultMode selects how many matching requests a fault fails
+type FaultMode string
+
+const (
+	// FaultOnce fails the first matching request
+	FaultOnce FaultMode = "once"
+	// FaultTimes fails the first Times matching requests
+	FaultTimes FaultMode = "times"
+	// FaultAlways fails every matching request
+	FaultAlways FaultMode = "always"
+	// FaultProbability fails each matching request with the given probability
+	FaultProbability FaultMode = "probability"
+)
+
+// ConnectionFaults disturb the connections of every broker. The zero value leaves
+// them alone.
+type ConnectionFaults struct {
+	// Latency delays every response, along with a random delay of up to Jitter
+	Latency time.Duration
+	Jitter  time.Duration
+	// Bandwidth limits the bytes per second written to each connection, 0 for no limit
+	Bandwidth int
+	// CloseAfterRequests closes connections once they have served that many requests
+	CloseAfterRequests int
+	// CloseMidResponse closes connections after writing half of their next response
+	CloseMidResponse bool
+	// HalfFrame writes only half of the next response of connections, then nothing
+	// more until their client gives up waiting for the rest and closes them
+	HalfFrame bool
+}
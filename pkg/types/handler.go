@@ -0,0 +1,82 @@
+package types
+
+import (
+	"context"
+	"io"
+)
+
+// RequestHeader is the decoded header of a request
+type RequestHeader struct {
+	APIKey        int16
+	APIVersion    int16
+	CorrelationID int32
+	ClientID      string
+}
+
+// Request is a request read from a client connection
+type Request struct {
+	Header RequestHeader
+	// Body is the encoded request following the header
+	Body []byte
+
+	ctx context.Context
+}
+
+// Context returns the context of the request, it holds the principal of the client
+// and is cancelled when the connection is closed
+func (r *Request) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// WithContext returns a shallow copy of the request with its context changed to ctx
+func (r *Request) WithContext(ctx context.Context) *Request {
+	r2 := *r
+	r2.ctx = ctx
+	return &r2
+}
+
+// ResponseWriter sends the response of a request to the client
+type ResponseWriter interface {
+	// Write writes raw bytes to the connection, they have to make up whole frames
+	io.Writer
+	// WriteResponse sends body as the response of the request, framed with its size
+	// and the response header matching the version of the request
+	WriteResponse(body []byte) error
+}
+
+// Handler serves the requests of an API. Returning an error closes the connection.
+// A handler which writes no response leaves the client waiting, like Kafka does for
+// produce requests with acks=0.
+type Handler interface {
+	ServeKafka(w ResponseWriter, r *Request) error
+}
+
+// HandlerFunc lets an ordinary function be used as a Handler
+type HandlerFunc func(w ResponseWriter, r *Request) error
+
+// ServeKafka calls f(w, r)
+func (f HandlerFunc) ServeKafka(w ResponseWriter, r *Request) error {
+	return f(w, r)
+}
+
+// Middleware wraps a handler, for instance to log, alter or fail requests before
+// they reach it
+type Middleware func(Handler) Handler
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx holding the principal of the client,
+// like User:alice
+func ContextWithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal of the client of a request. It is
+// empty until a client of a SASL listener has authenticated.
+func PrincipalFromContext(ctx context.Context) (string, bool) {
+	principal, ok := ctx.Value(principalKey{}).(string)
+	return principal, ok
+}
@@ -1,9 +1,159 @@
 // All common structs defined here
 package types
 
+import "time"
+
 type Params struct {
 	Addr  string
 	Port  int
 	Topic string
-	Data  chan map[string][]byte
+	// Data receives the values of the records of each produce request by key. The
+	// broker blocks sending to Data and Records until they are received or the
+	// server is closed.
+	Data chan map[string][]byte
+	// Records receives the records of each produce request, with their topic,
+	// partition, offset, headers and timestamp
+	Records chan []*Record
+
+	// Topics are created when the server starts, in addition to Topic
+	Topics []Topic
+
+	// ClusterID is returned by metadata responses, it defaults to kafka-mock
+	ClusterID string
+	// Rack is the rack of the broker returned by metadata responses
+	Rack string
+	// DisableAutoCreateTopics rejects metadata requests for unknown topics instead of
+	// creating them, like auto.create.topics.enable=false
+	DisableAutoCreateTopics bool
+	// DefaultPartitions is the partition count of auto created topics, it defaults to 1
+	DefaultPartitions int32
+	// DefaultReplicationFactor is the replication factor of topics created without one,
+	// like default.replication.factor. It defaults to 1.
+	DefaultReplicationFactor int16
+
+	// Brokers is the number of brokers of the mock cluster, it defaults to 1. Broker i,
+	// counting from 0, gets node ID i+1 and listens on the configured ports plus i
+	// times the number of listeners. Partition leaders and replicas are spread
+	// across the brokers.
+	Brokers int
+
+	// ReplicationDelay simulates how long followers take to acknowledge acks=-1 produce requests.
+	// Requests whose timeout is shorter than the delay fail with a request timed out error.
+	ReplicationDelay time.Duration
+	// ReplicationFailure makes every acks=-1 produce request fail in the given way
+	ReplicationFailure ReplicationFailure
+
+	// GroupInitialRebalanceDelay delays the first rebalance of an empty consumer group,
+	// like group.initial.rebalance.delay.ms. Unlike Kafka it defaults to no delay.
+	GroupInitialRebalanceDelay time.Duration
+	// GroupMinSessionTimeout and GroupMaxSessionTimeout bound the session timeouts of
+	// consumer group members, they default to 6 seconds and 30 minutes like Kafka
+	GroupMinSessionTimeout time.Duration
+	GroupMaxSessionTimeout time.Duration
+
+	// SASL requires clients to authenticate before any other request
+	SASL *SASL
+	// TLS serves clients over TLS instead of plaintext
+	TLS *TLS
+
+	// Listeners replace the single listener of Addr, Port, TLS and SASL. Metadata
+	// requests are answered with the advertised addresses of the listener they
+	// arrived on.
+	Listeners []Listener
+
+	// Faults inject errors into the matching requests, more can be added at runtime
+	Faults []Fault
+}
+
+// Listener is a named endpoint of the broker, like an entry of listeners and
+// advertised.listeners. Each listener has its own TLS and SASL settings.
+type Listener struct {
+	// Name identifies the listener, like PLAINTEXT or EXTERNAL
+	Name string `json:"name" yaml:"name"`
+	// Addr and Port are the address the listener binds to, a port of 0 picks a free port
+	Addr string `json:"addr" yaml:"addr"`
+	Port int    `json:"port" yaml:"port"`
+	// AdvertisedHost and AdvertisedPort are the address clients are redirected to,
+	// they default to Addr, or 127.0.0.1, and the port the listener bound
+	AdvertisedHost string `json:"advertisedHost" yaml:"advertisedHost"`
+	AdvertisedPort int    `json:"advertisedPort" yaml:"advertisedPort"`
+	TLS            *TLS   `json:"tls" yaml:"tls"`
+	SASL           *SASL  `json:"sasl" yaml:"sasl"`
+}
+
+// SASL configures the authentication of clients
+type SASL struct {
+	// Mechanisms are the enabled mechanisms out of PLAIN, SCRAM-SHA-256 and
+	// SCRAM-SHA-512, all of them are enabled by default
+	Mechanisms []string `json:"mechanisms" yaml:"mechanisms"`
+	// Users maps the names of the users to their passwords
+	Users map[string]string `json:"users" yaml:"users"`
+}
+
+// Topic declares a topic created when the server starts
+type Topic struct {
+	Name string `json:"name" yaml:"name"`
+	// Partitions defaults to 1
+	Partitions int32 `json:"partitions" yaml:"partitions"`
+	// ReplicationFactor defaults to Params.DefaultReplicationFactor
+	ReplicationFactor int16 `json:"replicationFactor" yaml:"replicationFactor"`
+	// Configs are topic configs like retention.ms, retention.bytes, cleanup.policy,
+	// compression.type and message.timestamp.type
+	Configs map[string]string `json:"configs" yaml:"configs"`
+}
+
+// TLS configures the TLS listener. Without CertFile and KeyFile the broker uses a
+// certificate signed by a CA generated in process.
+type TLS struct {
+	// CertFile and KeyFile are the PEM encoded certificate and key of the broker
+	CertFile string `json:"certFile" yaml:"certFile"`
+	KeyFile  string `json:"keyFile" yaml:"keyFile"`
+	// ClientAuth asks clients for a certificate, like ssl.client.auth
+	ClientAuth ClientAuth `json:"clientAuth" yaml:"clientAuth"`
+	// CAFile holds the PEM encoded CAs client certificates are verified with, it
+	// defaults to the generated CA
+	CAFile string `json:"caFile" yaml:"caFile"`
+}
+
+// ClientAuth selects whether clients have to present a certificate
+type ClientAuth string
+
+const (
+	// ClientAuthNone never asks for client certificates
+	ClientAuthNone ClientAuth = "none"
+	// ClientAuthRequested verifies the certificates of the clients which present one
+	ClientAuthRequested ClientAuth = "requested"
+	// ClientAuthRequired rejects clients without a valid certificate
+	ClientAuthRequired ClientAuth = "required"
+)
+
+// ReplicationFailure selects how acks=-1 produce requests fail
+type ReplicationFailure int
+
+const (
+	// ReplicationOK acknowledges acks=-1 produce requests after the replication delay
+	ReplicationOK ReplicationFailure = iota
+	// ReplicationTimeout appends the records but answers with a request timed out error
+	// once the request timeout has passed, like a leader whose followers never catch up
+	ReplicationTimeout
+	// ReplicationNotEnoughReplicas rejects the records with a not enough replicas error
+	// like a leader whose ISR is smaller than min.insync.replicas
+	ReplicationNotEnoughReplicas
+)
+
+// RecordHeader is a key value header attached to a record
+type RecordHeader struct {
+	Key   []byte
+	Value []byte
+}
+
+// Record is a single produced record as stored by the mock
+type Record struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   []*RecordHeader
+	Timestamp time.Time
 }